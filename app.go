@@ -0,0 +1,157 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+// Created on 2026-10-16
+
+package favicon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	urls "net/url"
+	"strings"
+)
+
+// AppLookup retrieves the icons of a native app from its app store.
+// appID identifies the app in the store, e.g. "284882215" for the
+// App Store or "com.example.app" for Google Play.
+type AppLookup func(ctx context.Context, client *http.Client, appID string) ([]*Icon, error)
+
+// WithAppStoreLookup looks up the app referenced by a page's Apple smart
+// app banner (<meta name="apple-itunes-app">) and returns its artwork.
+// Pass ITunesLookup to use Apple's public lookup API.
+func WithAppStoreLookup(lookup AppLookup) Option {
+	return func(f *Finder) {
+		f.appStoreLookup = lookup
+	}
+}
+
+// WithPlayStoreLookup retrieves the site's Digital Asset Links file
+// (/.well-known/assetlinks.json) and looks up the icons of any Android apps
+// it references. There is no public Play Store API, so you must provide
+// the AppLookup.
+func WithPlayStoreLookup(lookup AppLookup) Option {
+	return func(f *Finder) {
+		f.playStoreLookup = lookup
+	}
+}
+
+// iTunesLookupURL is the endpoint of the iTunes Search API.
+const iTunesLookupURL = "https://itunes.apple.com/lookup"
+
+// ITunesLookup is an AppLookup that retrieves app artwork via the iTunes
+// Search API.
+func ITunesLookup(ctx context.Context, client *http.Client, appID string) ([]*Icon, error) {
+	u := iTunesLookupURL + "?id=" + urls.QueryEscape(appID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("request URL: %w", err)
+	}
+	req.Header.Set("User-Agent", UserAgent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("retrieve URL: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &HTTPStatusError{Code: resp.StatusCode, URL: u}
+	}
+
+	var v struct {
+		Results []map[string]interface{} `json:"results"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&v); err != nil {
+		return nil, fmt.Errorf("parse lookup response: %w", err)
+	}
+
+	var icons []*Icon
+	for _, r := range v.Results {
+		for _, sz := range []int{60, 100, 512} {
+			s, _ := r[fmt.Sprintf("artworkUrl%d", sz)].(string)
+			if s != "" {
+				icons = append(icons, &Icon{URL: s, Width: sz, Height: sz, Kind: KindApp})
+			}
+		}
+	}
+	return icons, nil
+}
+
+// extract the app ID from the content of an apple-itunes-app meta tag,
+// e.g. "app-id=284882215, app-argument=https://example.com".
+func parseAppleAppID(content string) string {
+	for _, s := range strings.Split(content, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(s), "=")
+		if ok && strings.TrimSpace(k) == "app-id" {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
+
+// assetLink is a statement in an assetlinks.json file.
+type assetLink struct {
+	Target struct {
+		Namespace   string `json:"namespace"`
+		PackageName string `json:"package_name"`
+	} `json:"target"`
+}
+
+// return the Android package names referenced by the site's assetlinks.json.
+func (p *parser) androidPackages() []string {
+	if p.baseURL == nil {
+		return nil
+	}
+	u := resolveURL(p.baseURL, "/.well-known/assetlinks.json")
+	rc, err := p.find.fetchURL(p.ctx, u)
+	if err != nil {
+		p.probeFail("play-store", u, err)
+		return nil
+	}
+	defer rc.Close()
+
+	var links []assetLink
+	if err = json.NewDecoder(rc).Decode(&links); err != nil {
+		p.fail("play-store", u, fmt.Errorf("parse asset links: %w", err))
+		return nil
+	}
+
+	var (
+		names []string
+		seen  = map[string]bool{}
+	)
+	for _, l := range links {
+		name := l.Target.PackageName
+		if l.Target.Namespace != "android_app" || name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+// look up the icons of the site's Android apps.
+func (p *parser) playStoreSource() []*Icon {
+	var icons []*Icon
+	for _, name := range p.androidPackages() {
+		icons = append(icons, p.appSource("play-store", p.find.playStoreLookup, name)()...)
+	}
+	return icons
+}
+
+// return a source function that looks up an app's icons.
+func (p *parser) appSource(name string, lookup AppLookup, appID string) func() []*Icon {
+	return func() []*Icon {
+		icons, err := lookup(p.ctx, p.find.client, appID)
+		if err != nil {
+			p.fail(name, "", fmt.Errorf("look up %q: %w", appID, err))
+		}
+		for _, icon := range icons {
+			icon.Source = name
+			p.find.log.Printf("(%s) %s", name, icon.URL)
+		}
+		return icons
+	}
+}
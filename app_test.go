@@ -0,0 +1,114 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+// Created on 2026-10-16
+
+package favicon_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	urls "net/url"
+	"testing"
+
+	"github.com/muzhou233/go-favicon"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sends requests for other hosts to a test server.
+type rewriteTransport struct {
+	ts    *httptest.Server
+	paths map[string]string // host -> path on test server
+}
+
+func (rt rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if path, ok := rt.paths[req.URL.Host]; ok {
+		u, _ := urls.Parse(rt.ts.URL + path)
+		req = req.Clone(req.Context())
+		req.URL = u
+		req.Host = u.Host
+	}
+	return rt.ts.Client().Transport.RoundTrip(req)
+}
+
+// TestAppStoreLookup verifies lookup of apps in smart app banners.
+func TestAppStoreLookup(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name   string
+		opts   []favicon.Option
+		xcount int
+	}{
+		{"no-lookup", nil, 1},
+		{"itunes", []favicon.Option{favicon.WithAppStoreLookup(favicon.ITunesLookup)}, 4},
+		{"custom", []favicon.Option{favicon.WithAppStoreLookup(
+			func(_ context.Context, _ *http.Client, id string) ([]*favicon.Icon, error) {
+				assert.Equal(t, "284882215", id, "unexpected app ID")
+				return []*favicon.Icon{{URL: "/app-icon-1024x1024.png"}}, nil
+			})}, 2},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			ts := httptest.NewServer(http.FileServer(http.Dir("./testdata/app")))
+			defer ts.Close()
+
+			client := &http.Client{Transport: rewriteTransport{ts, map[string]string{
+				"itunes.apple.com": "/lookup.json",
+			}}}
+			opts := []favicon.Option{
+				favicon.WithClient(client),
+				favicon.WithLogger(debugLogger{t}),
+				favicon.IgnoreManifest,
+				favicon.IgnoreWellKnown,
+			}
+			f := favicon.New(append(opts, td.opts...)...)
+			icons, err := f.Find(ts.URL + "/index.html")
+			require.Nil(t, err, "unexpected error")
+			assert.Equal(t, td.xcount, len(icons), "unexpected favicon count")
+		})
+	}
+}
+
+// TestPlayStoreLookup verifies lookup of apps in asset links.
+func TestPlayStoreLookup(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name, path string
+		xcount     int
+	}{
+		{"asset-links", "./testdata/app", 2},
+		{"no-asset-links", "./testdata/kuli", 6},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			ts := httptest.NewServer(http.FileServer(http.Dir(td.path)))
+			defer ts.Close()
+
+			var calls int
+			f := favicon.New(
+				favicon.WithClient(ts.Client()),
+				favicon.WithLogger(debugLogger{t}),
+				favicon.IgnoreManifest,
+				favicon.IgnoreWellKnown,
+				favicon.WithPlayStoreLookup(func(_ context.Context, _ *http.Client, id string) ([]*favicon.Icon, error) {
+					calls++
+					assert.Equal(t, "com.example.app", id, "unexpected package name")
+					return []*favicon.Icon{{URL: "https://play-lh.googleusercontent.com/icon=s512.png",
+						Width: 512, Height: 512}}, nil
+				}),
+			)
+			icons, err := f.Find(ts.URL + "/index.html")
+			require.Nil(t, err, "unexpected error")
+			assert.Equal(t, td.xcount, len(icons), "unexpected favicon count")
+			assert.LessOrEqual(t, calls, 1, "package looked up more than once")
+		})
+	}
+}
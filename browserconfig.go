@@ -0,0 +1,95 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+// Created on 2026-10-16
+
+package favicon
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	urls "net/url"
+)
+
+// BrowserConfig is the relevant parts of a browserconfig.xml file, which
+// defines the tiles shown for a site on the Windows Start screen.
+type BrowserConfig struct {
+	Square70x70   BrowserConfigLogo `xml:"msapplication>tile>square70x70logo"`
+	Square150x150 BrowserConfigLogo `xml:"msapplication>tile>square150x150logo"`
+	Wide310x150   BrowserConfigLogo `xml:"msapplication>tile>wide310x150logo"`
+	Square310x310 BrowserConfigLogo `xml:"msapplication>tile>square310x310logo"`
+	TileColor     string            `xml:"msapplication>tile>TileColor"`
+}
+
+// BrowserConfigLogo is a tile image from a browserconfig.xml file.
+type BrowserConfigLogo struct {
+	URL string `xml:"src,attr"`
+}
+
+func (p *parser) parseBrowserConfig() []*Icon {
+	if p.configURL == "" {
+		return nil
+	}
+
+	url := p.configURL
+	p.find.log.Printf("loading browserconfig %q ...", url)
+	rc, err := p.find.fetchURL(p.ctx, url)
+	if err != nil {
+		if p.configDeclared {
+			p.fail("browserconfig", url, err)
+		} else {
+			p.probeFail("browserconfig", url, err)
+		}
+		return nil
+	}
+	defer rc.Close()
+
+	icons, err := p.parseBrowserConfigReader(rc, url)
+	if err != nil {
+		p.fail("browserconfig", url, err)
+	}
+	return icons
+}
+
+// extract tile images from browserconfig.xml. Image URLs are relative to
+// the file's URL.
+func (p *parser) parseBrowserConfigReader(r io.Reader, baseURL string) ([]*Icon, error) {
+	var bc BrowserConfig
+	if err := xml.NewDecoder(r).Decode(&bc); err != nil {
+		return nil, fmt.Errorf("parse browserconfig: %w", err)
+	}
+	base, err := urls.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("browserconfig URL: %w", err)
+	}
+
+	var icons []*Icon
+	for _, logo := range []struct {
+		BrowserConfigLogo
+		w, h int
+	}{
+		{bc.Square70x70, 70, 70},
+		{bc.Square150x150, 150, 150},
+		{bc.Wide310x150, 310, 150},
+		{bc.Square310x310, 310, 310},
+	} {
+		if logo.URL == "" {
+			continue
+		}
+		var u *urls.URL
+		if u, err = urls.Parse(logo.URL); err != nil {
+			continue
+		}
+		url := base.ResolveReference(u).String()
+		p.find.log.Printf("(browserconfig) %s", url)
+		icons = append(icons, &Icon{
+			URL:    url,
+			Width:  logo.w,
+			Height: logo.h,
+			Color:  bc.TileColor,
+			Kind:   KindTile,
+			Source: "browserconfig",
+		})
+	}
+	return icons, nil
+}
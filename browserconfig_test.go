@@ -0,0 +1,54 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+// Created on 2026-10-16
+
+package favicon_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/muzhou233/go-favicon"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBrowserConfig verifies tile images are read from browserconfig.xml.
+func TestBrowserConfig(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.FileServer(http.Dir("./testdata/browserconfig")))
+	defer ts.Close()
+
+	f := favicon.New(
+		favicon.WithClient(ts.Client()),
+		favicon.WithLogger(debugLogger{t}),
+		favicon.IgnoreManifest,
+		favicon.IgnoreWellKnown,
+	)
+	icons, err := f.Find(ts.URL + "/index.html")
+	require.Nil(t, err, "unexpected error")
+	require.Equal(t, 4, len(icons), "unexpected favicon count")
+
+	tests := []struct {
+		url           string
+		width, height int
+	}{
+		{ts.URL + "/config/mstile-large.png", 310, 310},
+		{"https://cdn.example.com/mstile-wide.png", 310, 150},
+		{ts.URL + "/mstile-medium.png", 150, 150},
+		{ts.URL + "/config/mstile-small.png", 70, 70},
+	}
+	for i, td := range tests {
+		assert.Equal(t, td.url, icons[i].URL, "unexpected URL")
+		assert.Equal(t, td.width, icons[i].Width, "unexpected width")
+		assert.Equal(t, td.height, icons[i].Height, "unexpected height")
+		assert.Equal(t, "#da532c", icons[i].Color, "unexpected colour")
+	}
+
+	// disabled by Option
+	icons, err = f.With(favicon.IgnoreBrowserConfig).Find(ts.URL + "/index.html")
+	require.Nil(t, err, "unexpected error")
+	assert.Equal(t, 0, len(icons), "unexpected favicon count")
+}
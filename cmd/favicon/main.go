@@ -33,6 +33,7 @@ var (
 	flagCSV     = fs.Bool("csv", false, "output favicon list as CSV")
 	flagTSV     = fs.Bool("tsv", false, "output favicon list as TSV")
 	flagSquare  = fs.Bool("square", false, "only show square icons")
+	flagRules   = fs.String("rules", "", "JSON `file` of extra locations to check per domain")
 	flagVerbose = fs.Bool("v", false, "show informational messages")
 	flagVersion = fs.Bool("version", false, "show version number and exit")
 
@@ -42,10 +43,14 @@ var (
 
 func usage() {
 	fmt.Fprint(fs.Output(),
-		`usage: favicon <url>
+		`usage: favicon [options] <url>
+       favicon [options] record <url> <directory>
 
 retrieve, favicons for URL
 
+The record command saves the page, manifest etc. and icons to directory,
+in the layout used by the package's test data.
+
 `)
 	fs.PrintDefaults()
 }
@@ -69,29 +74,36 @@ func main() {
 		return
 	}
 
-	u := fs.Arg(0)
-	s := strings.ToLower(u)
-	if !strings.HasPrefix(s, "http://") && !strings.HasPrefix(s, "https://") {
-		log.Fatalf("invalid URL: %q", s)
-	}
-
 	if *flagVerbose {
 		opts = append(opts, favicon.WithLogger(log))
 	}
 
+	if *flagRules != "" {
+		rules, err := favicon.LoadRules(*flagRules)
+		checkErr(err)
+		opts = append(opts, favicon.WithRules(rules...))
+	}
+
+	if fs.Arg(0) == "record" {
+		if fs.NArg() != 3 {
+			usage()
+			os.Exit(1)
+		}
+		checkURL(fs.Arg(1))
+		checkErr(record(fs.Arg(1), fs.Arg(2)))
+		return
+	}
+
+	u := fs.Arg(0)
+	checkURL(u)
+
 	f := favicon.New(opts...)
 	icons, err := f.Find(u)
 	checkErr(err)
 	// log.Printf("%d icon(s) found for %q", len(icons), u)
 
 	if *flagSquare {
-		var clean []*favicon.Icon
-		for _, icon := range icons {
-			if icon.IsSquare() {
-				clean = append(clean, icon)
-			}
-		}
-		icons = clean
+		icons = icons.SquareOnly()
 	}
 
 	if *flagJSON {
@@ -127,6 +139,14 @@ func main() {
 	fmt.Println(t.Render())
 }
 
+// exit if url is not an HTTP URL.
+func checkURL(url string) {
+	s := strings.ToLower(url)
+	if !strings.HasPrefix(s, "http://") && !strings.HasPrefix(s, "https://") {
+		log.Fatalf("invalid URL: %q", s)
+	}
+}
+
 func checkErr(err error) {
 	if err != nil {
 		log.Fatal(err)
@@ -0,0 +1,116 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+// Created on 2026-10-16
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	urls "net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/muzhou233/go-favicon"
+)
+
+// record saves the files retrieved while finding icons for url, and the
+// icons themselves, to dir in the layout used by the package's testdata,
+// i.e. a directory that can be served with http.FileServer.
+func record(url, dir string) error {
+	u, err := urls.Parse(url)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+	rec := &recorder{dir: dir, host: u.Host, rt: http.DefaultTransport, saved: map[string]bool{}}
+	client := &http.Client{Transport: rec}
+
+	f := favicon.New(append(opts, favicon.WithClient(client))...)
+	res, err := f.FindDetailed(url)
+	if err != nil {
+		return err
+	}
+	// retrieve icons on the same server so they are recorded, too
+	for _, icon := range res.Icons {
+		if iu, err1 := urls.Parse(icon.URL); err1 != nil || iu.Host != u.Host {
+			continue
+		}
+		if err = fetch(client, icon.URL); err != nil {
+			log.Printf("[ERROR] %s: %v", icon.URL, err)
+		}
+	}
+
+	log.Printf("%d icon(s) found, %d file(s) saved to %s", len(res.Icons), len(rec.saved), dir)
+	log.Printf("page is at %s", pagePath(u))
+	return nil
+}
+
+// retrieve URL, discarding the response.
+func fetch(client *http.Client, url string) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", favicon.UserAgent)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(io.Discard, resp.Body)
+	return err
+}
+
+// return the path a page is saved at, relative to the recording directory.
+func pagePath(u *urls.URL) string {
+	p := path.Clean("/" + u.Path)
+	if strings.HasSuffix(u.Path, "/") || p == "/" {
+		p = path.Join(p, "index.html")
+	}
+	return p
+}
+
+// recorder is an http.RoundTripper that saves successful responses from
+// host to dir. Files are saved at the request path; responses for
+// directories are saved as index.html.
+type recorder struct {
+	dir  string
+	host string
+	rt   http.RoundTripper
+
+	mu    sync.Mutex
+	saved map[string]bool // paths of files written
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := r.rt.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK || req.URL.Host != r.host {
+		return resp, err
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p := filepath.Join(r.dir, filepath.FromSlash(pagePath(req.URL)))
+	if err = os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return nil, err
+	}
+	if err = os.WriteFile(p, data, 0o600); err != nil {
+		return nil, err
+	}
+	r.saved[p] = true
+	log.Printf("saved %s", p)
+	return resp, nil
+}
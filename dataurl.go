@@ -0,0 +1,159 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+// Created on 2026-10-16
+
+package favicon
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"  // register decoder for DecodeConfig
+	_ "image/jpeg" // register decoder for DecodeConfig
+	_ "image/png"  // register decoder for DecodeConfig
+	"mime"
+	urls "net/url"
+	"strings"
+)
+
+// ErrNotDataURL is returned by Icon.Data if the Icon's URL is not a data: URL.
+var ErrNotDataURL = errors.New("not a data: URL")
+
+// Data returns the contents of an Icon embedded in the page as a data: URL,
+// e.g. <link rel="icon" href="data:image/png;base64,...">. It returns
+// ErrNotDataURL for Icons that must be fetched from the server.
+func (i Icon) Data() ([]byte, error) {
+	if !isDataURL(i.URL) {
+		return nil, ErrNotDataURL
+	}
+	_, data, err := parseDataURL(i.URL)
+	return data, err
+}
+
+// returns true if url is a data: URL.
+func isDataURL(url string) bool {
+	return len(url) > 5 && strings.EqualFold(url[:5], "data:")
+}
+
+// parse data:[<mediatype>][;base64],<data> URL.
+func parseDataURL(url string) (string, []byte, error) {
+	if !isDataURL(url) {
+		return "", nil, ErrNotDataURL
+	}
+	meta, payload, ok := strings.Cut(url[5:], ",")
+	if !ok {
+		return "", nil, errors.New("invalid data: URL: no data")
+	}
+
+	isBase64 := false
+	if strings.HasSuffix(strings.ToLower(meta), ";base64") {
+		isBase64 = true
+		meta = meta[:len(meta)-len(";base64")]
+	}
+	mimeType, _, err := mime.ParseMediaType(meta)
+	if err != nil {
+		mimeType = ""
+	}
+
+	var data []byte
+	if isBase64 {
+		// whitespace is often included in markup
+		payload = strings.Join(strings.Fields(payload), "")
+		if data, err = base64.StdEncoding.DecodeString(payload); err != nil {
+			if data, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(payload, "=")); err != nil {
+				return "", nil, fmt.Errorf("invalid data: URL: %w", err)
+			}
+		}
+	} else {
+		var s string
+		if s, err = urls.PathUnescape(payload); err != nil {
+			return "", nil, fmt.Errorf("invalid data: URL: %w", err)
+		}
+		data = []byte(s)
+	}
+	return mimeType, data, nil
+}
+
+// set MIME type and dimensions of an Icon with a data: URL from its contents.
+func (p *parser) inspectDataURL(icon *Icon) {
+	declared, data, err := parseDataURL(icon.URL)
+	if err != nil {
+		p.find.log.Printf("[WARNING] %v", err)
+		return
+	}
+
+	if icon.MimeType == "" {
+		if icon.MimeType = sniffImageType(data); icon.MimeType == "" && strings.HasPrefix(declared, "image/") {
+			icon.MimeType = declared
+		}
+	}
+
+	if icon.Width == 0 {
+		if w, h, ok := imageSize(data); ok {
+			icon.Width, icon.Height = w, h
+		}
+	}
+}
+
+// return the dimensions of PNG, GIF, JPEG or ICO image data.
+func imageSize(data []byte) (int, int, bool) {
+	if cfg, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		return cfg.Width, cfg.Height, true
+	}
+	return icoSize(data)
+}
+
+// return the sizes of all the images in image data: one for most formats,
+// but an ICO file may contain several. Returns nil if the size is unknown.
+func imageSizes(data []byte) []size {
+	if cfg, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		return []size{{w: cfg.Width, h: cfg.Height}}
+	}
+	return icoSizes(data)
+}
+
+// return the size of the largest image in ICO file data.
+func icoSize(data []byte) (int, int, bool) {
+	var w, h int
+	for _, sz := range icoSizes(data) {
+		if sz.w*sz.h > w*h {
+			w, h = sz.w, sz.h
+		}
+	}
+	return w, h, w > 0
+}
+
+// return the sizes in the directory of ICO file data, or nil if it isn't an
+// ICO file.
+func icoSizes(data []byte) []size {
+	const (
+		headerSize = 6
+		entrySize  = 16
+		maxSize    = 256 // stored as 0
+	)
+	if len(data) < headerSize ||
+		binary.LittleEndian.Uint16(data[0:]) != 0 || binary.LittleEndian.Uint16(data[2:]) != 1 {
+		return nil
+	}
+
+	var sizes []size
+	n := int(binary.LittleEndian.Uint16(data[4:]))
+	for i := 0; i < n; i++ {
+		off := headerSize + i*entrySize
+		if len(data) < off+entrySize {
+			break
+		}
+		ew, eh := int(data[off]), int(data[off+1])
+		if ew == 0 {
+			ew = maxSize
+		}
+		if eh == 0 {
+			eh = maxSize
+		}
+		sizes = append(sizes, size{w: ew, h: eh})
+	}
+	return sizes
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+// Created on 2026-10-16
+
+package favicon_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/muzhou233/go-favicon"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDataURL verifies icons embedded as data: URLs are inspected.
+func TestDataURL(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	require.Nil(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 24, 16))), "unexpected error")
+	pngData := buf.Bytes()
+	// ICO header with a 48x48 and a 256x256 image
+	icoData := []byte{0, 0, 1, 0, 2, 0,
+		48, 48, 0, 0, 1, 0, 32, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 1, 0, 32, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	svg := `<svg xmlns='http://www.w3.org/2000/svg'><circle fill='#f00' r='8'/></svg>`
+
+	tests := []struct {
+		name, href    string
+		mimeType      string
+		width, height int
+		data          []byte
+	}{
+		{"png", "data:image/png;base64," + base64.StdEncoding.EncodeToString(pngData), "image/png", 24, 16, pngData},
+		{"undeclared-type", "data:;base64," + base64.StdEncoding.EncodeToString(pngData), "image/png", 24, 16, pngData},
+		{"ico", "data:image/x-icon;base64," + base64.StdEncoding.EncodeToString(icoData), "image/x-icon", 256, 256, icoData},
+		{"svg", "data:image/svg+xml," + strings.ReplaceAll(svg, "#", "%23"), "image/svg+xml", 0, 0, []byte(svg)},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			html := `<html><head><link rel="icon" href="` + td.href + `"></head></html>`
+			icons, err := htmlFinder(t).FindReader(strings.NewReader(html), favicon.BaseURL("https://example.com/"))
+			require.Nil(t, err, "unexpected error")
+			require.Equal(t, 1, len(icons), "unexpected favicon count")
+
+			icon := icons[0]
+			assert.Equal(t, td.href, icon.URL, "data: URL not preserved")
+			assert.Equal(t, td.mimeType, icon.MimeType, "unexpected MIME type")
+			assert.Equal(t, td.width, icon.Width, "unexpected width")
+			assert.Equal(t, td.height, icon.Height, "unexpected height")
+
+			data, err := icon.Data()
+			require.Nil(t, err, "unexpected error")
+			assert.Equal(t, td.data, data, "unexpected data")
+		})
+	}
+
+	_, err := favicon.Icon{URL: "https://example.com/favicon.ico"}.Data()
+	assert.True(t, errors.Is(err, favicon.ErrNotDataURL), "unexpected error: %v", err)
+}
@@ -0,0 +1,39 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+// Created on 2026-10-16
+
+package favicon
+
+import "time"
+
+// SkipLowValueWithin sets a policy for finishing before the deadline of the
+// context passed to FindContext or FindStream: if less than d remains when a
+// low-value source is due to start, it is skipped, leaving the time to the
+// page, manifest and other files the page references. Low-value sources are
+// run last. Currently, only the checks of well-known locations like
+// /favicon.ico are low-value.
+//
+// Skipped sources are recorded in Result.Errors with ErrSkipped.
+func SkipLowValueWithin(d time.Duration) Option {
+	return func(f *Finder) { f.lowValueCutoff = d }
+}
+
+// wrap a low-value source so it is skipped if the context's deadline is
+// closer than the Finder's cutoff.
+func (p *parser) lowValue(name string, fn func() []*Icon) func() []*Icon {
+	return func() []*Icon {
+		if p.find.lowValueCutoff <= 0 {
+			return fn()
+		}
+		deadline, ok := p.ctx.Deadline()
+		if !ok {
+			return fn()
+		}
+		if left := time.Until(deadline); left < p.find.lowValueCutoff {
+			p.find.log.Printf("skipping %s: %v left before deadline", name, left.Round(time.Millisecond))
+			p.fail(name, "", ErrSkipped)
+			return nil
+		}
+		return fn()
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+// Created on 2026-10-16
+
+package favicon_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/muzhou233/go-favicon"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSkipLowValueWithin verifies well-known locations aren't checked if
+// the deadline is near.
+func TestSkipLowValueWithin(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		cutoff   time.Duration
+		timeout  time.Duration // no deadline if 0
+		xskipped bool
+	}{
+		{"no policy", 0, time.Minute, false},
+		{"no deadline", time.Hour, 0, false},
+		{"plenty of time", time.Second, time.Minute, false},
+		{"deadline near", time.Hour, time.Minute, true},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			var probes int32
+			mux := http.NewServeMux()
+			mux.Handle("/", http.FileServer(http.Dir("./testdata/kuli")))
+			mux.HandleFunc("/favicon.ico", func(w http.ResponseWriter, _ *http.Request) {
+				atomic.AddInt32(&probes, 1)
+				http.NotFound(w, nil)
+			})
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			ctx := context.Background()
+			if td.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, td.timeout)
+				defer cancel()
+			}
+			f := favicon.New(favicon.WithClient(ts.Client()), favicon.WithLogger(debugLogger{t}),
+				favicon.SkipLowValueWithin(td.cutoff))
+			res, err := f.FindContext(ctx, ts.URL+"/index.html")
+			require.Nil(t, err, "unexpected error")
+			assert.Equal(t, 8, len(res.Icons), "unexpected favicon count")
+
+			var skipped bool
+			for _, e := range res.Errors {
+				if errors.Is(e, favicon.ErrSkipped) {
+					assert.Equal(t, "well-known", e.Source, "unexpected source")
+					skipped = true
+				}
+			}
+			assert.Equal(t, td.xskipped, skipped, "unexpected skip")
+			assert.Equal(t, td.xskipped, atomic.LoadInt32(&probes) == 0, "unexpected probes")
+		})
+	}
+}
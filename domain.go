@@ -0,0 +1,152 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+// Created on 2026-10-16
+
+package favicon
+
+import (
+	"net/http"
+	urls "net/url"
+	"strings"
+)
+
+// options that only apply to one domain and its subdomains.
+type domainOptions struct {
+	domain  string
+	options []Option
+}
+
+// ForDomain applies options only when finding icons for URLs on domain or
+// its subdomains, e.g. to send an extra header to one site or skip a
+// manifest that is known to be broken:
+//
+//	f := favicon.New(
+//		favicon.ForDomain("example.com", favicon.IgnoreManifest),
+//		favicon.ForDomain("example.org", favicon.WithHeader("Cookie", "consent=1")),
+//	)
+//
+// Options for a domain are applied after the Finder's other options, in the
+// order they were given. The domain is chosen by the URL passed to Find etc.
+// and is not changed by redirects. Headers added by WithHeader are the
+// exception: they are only sent to hosts on the domain, not to e.g. a CDN,
+// manifest or oEmbed endpoint on another server that the page links to.
+func ForDomain(domain string, option ...Option) Option {
+	domain = strings.ToLower(strings.Trim(domain, "."))
+	return func(f *Finder) {
+		f.domains = append(f.domains, domainOptions{domain, option})
+	}
+}
+
+// WithHeader configures Finder to send an additional HTTP header with every
+// request. It cannot override the User-Agent header; use a custom
+// http.Client for that.
+func WithHeader(key, value string) Option {
+	return func(f *Finder) {
+		if f.header == nil {
+			f.header = http.Header{}
+		}
+		f.header.Add(key, value)
+	}
+}
+
+// WithManifestCredentials configures Finder to send an HTTP header, e.g.
+// Cookie or Authorization, when it retrieves a manifest whose <link> tag (or
+// Link header) has crossorigin="use-credentials". Browsers only send
+// credentials with manifest requests in that case, so sites whose manifest
+// requires a login declare it. Unlike WithHeader, the header is not sent
+// with any other request.
+//
+// So that a page can't collect the credentials by linking to a manifest on
+// another server, the header is only sent if the manifest has the same
+// origin as the page, or is on one of hosts.
+func WithManifestCredentials(key, value string, hosts ...string) Option {
+	return func(f *Finder) {
+		if f.manifestHeader == nil {
+			f.manifestHeader = http.Header{}
+		}
+		f.manifestHeader.Add(key, value)
+		for _, h := range hosts {
+			f.manifestHosts = append(f.manifestHosts, strings.ToLower(h))
+		}
+	}
+}
+
+// return true if a and b have the same scheme, host and port.
+func sameOrigin(a, b *urls.URL) bool {
+	if a == nil || b == nil || !strings.EqualFold(a.Scheme, b.Scheme) {
+		return false
+	}
+	port := func(u *urls.URL) string {
+		if p := u.Port(); p != "" {
+			return p
+		}
+		return defaultPort(strings.ToLower(u.Scheme))
+	}
+	return strings.EqualFold(a.Hostname(), b.Hostname()) && port(a) == port(b)
+}
+
+// headers added by ForDomain's options, which are only sent to the domain.
+type domainHeader struct {
+	domain string
+	header http.Header
+}
+
+// return true if host is domain or one of its subdomains.
+func onDomain(host, domain string) bool {
+	host = strings.ToLower(host)
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// return Finder with options for URL's domain applied. Returns f if there
+// are none.
+func (f *Finder) forURL(u *urls.URL) *Finder {
+	if u == nil {
+		return f
+	}
+	var (
+		host = strings.ToLower(u.Hostname())
+		ff   = f
+		n    int
+	)
+	for _, d := range f.domains {
+		if !onDomain(host, d.domain) {
+			continue
+		}
+		header := ff.header
+		ff = ff.With(d.options...)
+		// move headers the options added to domainHeaders
+		added := http.Header{}
+		for k, v := range ff.header {
+			if v = v[len(header[k]):]; len(v) > 0 {
+				added[k] = v
+			}
+		}
+		ff.header = header.Clone()
+		if len(added) > 0 {
+			ff.domainHeaders = append(ff.domainHeaders, domainHeader{d.domain, added})
+		}
+		n += len(d.options)
+	}
+	if n > 0 {
+		f.log.Printf("applying %d option(s) for %s", n, host)
+	}
+	return ff
+}
+
+// return the headers to send to host: those added by WithHeader, plus those
+// added by ForDomain for host's domain.
+func (f *Finder) headerFor(host string) http.Header {
+	header := f.header.Clone()
+	for _, d := range f.domainHeaders {
+		if !onDomain(host, d.domain) {
+			continue
+		}
+		if header == nil {
+			header = http.Header{}
+		}
+		for k, v := range d.header {
+			header[k] = append(header[k], v...)
+		}
+	}
+	return header
+}
@@ -0,0 +1,230 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+// Created on 2026-10-16
+
+package favicon_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/muzhou233/go-favicon"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestForDomain verifies Options are only applied to matching domains.
+func TestForDomain(t *testing.T) {
+	t.Parallel()
+	var (
+		mu    sync.Mutex
+		paths = map[string]string{} // path -> X-Test header
+		fs    = http.FileServer(http.Dir("./testdata/kuli"))
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths[r.URL.Path] = r.Header.Get("X-Test")
+		mu.Unlock()
+		fs.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	f := favicon.New(
+		favicon.WithClient(ts.Client()),
+		favicon.WithLogger(debugLogger{t}),
+		favicon.IgnoreWellKnown,
+		favicon.IgnoreBrowserConfig,
+		favicon.ForDomain("127.0.0.1", favicon.IgnoreManifest, favicon.WithHeader("X-Test", "yes")),
+		favicon.ForDomain("example.com", favicon.OnlyPNG),
+	)
+	icons, err := f.Find(ts.URL + "/index.html")
+	require.Nil(t, err, "unexpected error")
+	assert.Equal(t, 6, len(icons), "unexpected favicon count")
+	// FileServer redirects /index.html to /
+	assert.Equal(t, map[string]string{"/index.html": "yes", "/": "yes"}, paths, "unexpected requests")
+
+	// subdomains match
+	html := `<link rel="icon" href="/favicon.ico"><link rel="icon" href="/favicon.png">`
+	f = htmlFinder(t, favicon.ForDomain("Example.COM", favicon.OnlyPNG))
+	for url, x := range map[string]int{
+		"https://example.com/":       1,
+		"https://www.example.com/":   1,
+		"https://notexample.com/":    2,
+		"https://example.com.co.uk/": 2,
+	} {
+		icons, err = f.FindReader(strings.NewReader(html), favicon.BaseURL(url))
+		require.Nil(t, err, "unexpected error")
+		assert.Equal(t, x, len(icons), "unexpected favicon count for "+url)
+	}
+}
+
+// TestForDomainHeader verifies headers added by ForDomain aren't sent to
+// other hosts, whether the page links to them or redirects to them.
+func TestForDomainHeader(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name, manifest string
+	}{
+		{"link", "https://cdn.example.com/manifest.json"},
+		{"redirect", "/manifest.json"},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			var (
+				mu    sync.Mutex
+				paths = map[string]string{} // path -> X-Test header
+			)
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				paths[r.URL.Path] = r.Header.Get("X-Test")
+				mu.Unlock()
+				switch r.URL.Path {
+				case "/":
+					_, _ = w.Write([]byte(`<link rel="manifest" href="` + td.manifest + `">`))
+				case "/manifest.json":
+					http.Redirect(w, r, "https://cdn.example.com/manifest.json", http.StatusFound)
+				case "/cdn":
+					_, _ = w.Write([]byte(`{"icons": [{"src": "/icon-192.png", "sizes": "192x192"}]}`))
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer ts.Close()
+
+			client := &http.Client{Transport: rewriteTransport{ts, map[string]string{"cdn.example.com": "/cdn"}}}
+			f := favicon.New(
+				favicon.WithClient(client),
+				favicon.WithLogger(debugLogger{t}),
+				favicon.IgnoreWellKnown,
+				favicon.IgnoreBrowserConfig,
+				favicon.ForDomain("127.0.0.1", favicon.WithHeader("X-Test", "yes")),
+			)
+			icons, err := f.Find(ts.URL + "/")
+			require.Nil(t, err, "unexpected error")
+			assert.Equal(t, 1, len(icons), "unexpected favicon count")
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, "yes", paths["/"], "header not sent to domain")
+			assert.Equal(t, "", paths["/cdn"], "header sent to other host")
+		})
+	}
+}
+
+// TestManifestCredentials verifies credentials are only sent with manifest
+// requests the page marks crossorigin="use-credentials".
+func TestManifestCredentials(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name, html, link string
+		xicons           int
+	}{
+		{"use-credentials", `<link rel="manifest" href="/manifest.json" crossorigin="use-credentials">`, "", 1},
+		{"anonymous", `<link rel="manifest" href="/manifest.json" crossorigin="anonymous">`, "", 0},
+		{"no attribute", `<link rel="manifest" href="/manifest.json">`, "", 0},
+		{"Link header", `<title>Test</title>`, `</manifest.json>; rel="manifest"; crossorigin="Use-Credentials"`, 1},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			var (
+				mu      sync.Mutex
+				headers = map[string]string{} // path -> Authorization header
+			)
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				auth := r.Header.Get("Authorization")
+				mu.Lock()
+				headers[r.URL.Path] = auth
+				mu.Unlock()
+				if r.URL.Path == "/manifest.json" {
+					if auth != "Bearer secret" {
+						w.WriteHeader(http.StatusUnauthorized)
+						return
+					}
+					_, _ = w.Write([]byte(`{"icons": [{"src": "/icon-192.png", "sizes": "192x192"}]}`))
+					return
+				}
+				if td.link != "" {
+					w.Header().Set("Link", td.link)
+				}
+				w.Header().Set("Content-Type", "text/html")
+				_, _ = w.Write([]byte(td.html))
+			}))
+			defer ts.Close()
+
+			f := favicon.New(
+				favicon.WithClient(ts.Client()),
+				favicon.WithLogger(debugLogger{t}),
+				favicon.IgnoreWellKnown,
+				favicon.IgnoreBrowserConfig,
+				favicon.WithManifestCredentials("Authorization", "Bearer secret"),
+			)
+			icons, err := f.Find(ts.URL + "/")
+			require.Nil(t, err, "unexpected error")
+			assert.Equal(t, td.xicons, len(icons), "unexpected favicon count")
+			// credentials are never sent with the page request
+			assert.Equal(t, "", headers["/"], "unexpected Authorization header")
+		})
+	}
+}
+
+// TestManifestCredentialsOrigin verifies credentials are only sent to a
+// manifest on another server if its host was passed to
+// WithManifestCredentials.
+func TestManifestCredentialsOrigin(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		hosts []string
+		xauth string
+	}{
+		{"cross-origin", nil, ""},
+		{"other host", []string{"example.com"}, ""},
+		{"allowed host", []string{"127.0.0.1"}, "Bearer secret"},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			var (
+				mu   sync.Mutex
+				auth string
+			)
+			other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				auth = r.Header.Get("Authorization")
+				mu.Unlock()
+				_, _ = w.Write([]byte(`{"icons": [{"src": "/icon-192.png", "sizes": "192x192"}]}`))
+			}))
+			defer other.Close()
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html")
+				_, _ = w.Write([]byte(`<link rel="manifest" href="` + other.URL +
+					`/manifest.json" crossorigin="use-credentials">`))
+			}))
+			defer ts.Close()
+
+			f := favicon.New(
+				favicon.WithLogger(debugLogger{t}),
+				favicon.IgnoreWellKnown,
+				favicon.IgnoreBrowserConfig,
+				favicon.WithManifestCredentials("Authorization", "Bearer secret", td.hosts...),
+			)
+			icons, err := f.Find(ts.URL + "/")
+			require.Nil(t, err, "unexpected error")
+			assert.Equal(t, 1, len(icons), "unexpected favicon count")
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, td.xauth, auth, "unexpected Authorization header")
+		})
+	}
+}
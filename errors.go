@@ -0,0 +1,140 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+// Created on 2026-10-16
+
+package favicon
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrInvalidHTML is returned if a page cannot be parsed as HTML.
+	ErrInvalidHTML = errors.New("invalid HTML")
+	// ErrNoIcons is returned by a Finder created with the FailWhenEmpty
+	// Option if no icons are found.
+	ErrNoIcons = errors.New("no icons found")
+	// ErrSkipped is recorded in Result.Errors for sources skipped because
+	// the context's deadline was near. See SkipLowValueWithin.
+	ErrSkipped = errors.New("skipped: deadline too close")
+)
+
+// HTTPStatusError is returned when a server responds with a status other
+// than 200 OK. Use errors.As to retrieve it from errors returned by Finder:
+//
+//	var e *HTTPStatusError
+//	if errors.As(err, &e) && e.Code == http.StatusNotFound {
+//		...
+//	}
+type HTTPStatusError struct {
+	Code int    // HTTP status code
+	URL  string // URL requested
+}
+
+// Error implements error.
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("[%d] %s: %s", e.Code, http.StatusText(e.Code), e.URL)
+}
+
+// RedirectLoopError is returned when HTTP redirects lead back to a URL that
+// was already requested. <meta http-equiv="refresh"> redirects and frames
+// that do so aren't errors; the page that leads back is used.
+type RedirectLoopError struct {
+	URLs []string // the loop, starting and ending with the repeated URL
+}
+
+// Error implements error.
+func (e *RedirectLoopError) Error() string {
+	return "redirect loop: " + strings.Join(e.URLs, " -> ")
+}
+
+// return a *RedirectLoopError if url is in visited, otherwise nil.
+func checkLoop(url string, visited []string) error {
+	for i, s := range visited {
+		if s == url {
+			loop := append(append([]string{}, visited[i:]...), url)
+			return &RedirectLoopError{URLs: loop}
+		}
+	}
+	return nil
+}
+
+// OptionError describes Options passed to New() that contradict each other,
+// so a Finder configured with them could never return any icons.
+// It is returned by Finder.Validate.
+type OptionError struct {
+	Options []string // the conflicting Options, e.g. OnlyMimeType("image/png")
+	Reason  string   // why they conflict
+}
+
+// Error implements error.
+func (e *OptionError) Error() string {
+	return fmt.Sprintf("conflicting options %s: %s", strings.Join(e.Options, ", "), e.Reason)
+}
+
+// FindError is a failure of one source of icons, e.g. the manifest could
+// not be retrieved. Such failures don't stop a search; they are collected
+// in Result.Errors.
+type FindError struct {
+	Source string // where Finder was looking, e.g. "manifest" or "well-known"
+	URL    string // URL being retrieved; may be empty
+	Err    error  // underlying error
+}
+
+// Error implements error.
+func (e *FindError) Error() string {
+	if e.URL == "" {
+		return fmt.Sprintf("%s: %v", e.Source, e.Err)
+	}
+	return fmt.Sprintf("%s: %s: %v", e.Source, e.URL, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *FindError) Unwrap() error { return e.Err }
+
+// FindErrors is a list of source failures. It implements error, and
+// errors.As and errors.Is inspect each FindError in turn.
+type FindErrors []*FindError
+
+// Error implements error.
+func (v FindErrors) Error() string {
+	s := make([]string, len(v))
+	for i, err := range v {
+		s[i] = err.Error()
+	}
+	return strings.Join(s, "; ")
+}
+
+// Unwrap returns the individual errors.
+func (v FindErrors) Unwrap() []error {
+	errs := make([]error, len(v))
+	for i, err := range v {
+		errs[i] = err
+	}
+	return errs
+}
+
+// record the failure to retrieve an optional file from a common location,
+// e.g. /favicon.ico. Most sites don't have all of them, so a missing file
+// (404 Not Found or 410 Gone) is only logged.
+func (p *parser) probeFail(source, url string, err error) {
+	var e *HTTPStatusError
+	if errors.As(err, &e) && (e.Code == http.StatusNotFound || e.Code == http.StatusGone) {
+		p.find.log.Printf("(%s) not found: %s", source, url)
+		return
+	}
+	p.fail(source, url, err)
+}
+
+// log and record the failure of a source.
+func (p *parser) fail(source, url string, err error) {
+	fe := &FindError{Source: source, URL: url, Err: err}
+	p.find.log.Printf("[ERROR] %v", fe)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.result.Errors = append(p.result.Errors, fe)
+}
@@ -0,0 +1,227 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+// Created on 2026-10-16
+
+package favicon_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/muzhou233/go-favicon"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFindErrors verifies failures of individual sources are reported, but
+// files missing from common locations like /favicon.ico are not.
+func TestFindErrors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name, path string
+		status     int // if set, the status of responses for files other than the page
+		xsources   []string
+	}{
+		{"github", "./testdata/github", 0, nil},
+		{"html-only", "./testdata/mozilla", 0, nil},
+		{"no-markup", "./testdata/no-markup", 0, nil},
+		{"server-error", "./testdata/mozilla", http.StatusInternalServerError,
+			[]string{"manifest", "manifest", "manifest", "browserconfig", "well-known", "well-known"}},
+		{"forbidden", "./testdata/github", http.StatusForbidden,
+			[]string{"manifest", "browserconfig", "well-known", "well-known"}},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			fs := http.FileServer(http.Dir(td.path))
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if td.status != 0 && r.URL.Path != "/" && r.URL.Path != "/index.html" {
+					w.WriteHeader(td.status)
+					return
+				}
+				fs.ServeHTTP(w, r)
+			}))
+			defer ts.Close()
+
+			f := favicon.New(favicon.WithClient(ts.Client()), favicon.WithLogger(debugLogger{t}))
+			res, err := f.FindDetailed(ts.URL + "/index.html")
+			require.Nil(t, err, "unexpected error")
+			assert.NotEmpty(t, res.Icons, "no icons found")
+			if td.xsources == nil {
+				assert.Nil(t, res.Err(), "unexpected error")
+				return
+			}
+			require.Equal(t, len(td.xsources), len(res.Errors), "unexpected error count")
+
+			var sources []string
+			for _, fe := range res.Errors {
+				sources = append(sources, fe.Source)
+			}
+			assert.ElementsMatch(t, td.xsources, sources, "unexpected error sources")
+
+			var fe *favicon.FindError
+			require.True(t, errors.As(res.Err(), &fe), "no FindError")
+			assert.NotEmpty(t, fe.URL, "empty URL")
+		})
+	}
+}
+
+// TestHTTPStatusError verifies HTTP errors can be inspected.
+func TestHTTPStatusError(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.FileServer(http.Dir("./testdata/github")))
+	defer ts.Close()
+
+	f := favicon.New(favicon.WithClient(ts.Client()), favicon.WithLogger(debugLogger{t}))
+	_, err := f.Find(ts.URL + "/missing.html")
+	require.NotNil(t, err, "expected error")
+
+	var e *favicon.HTTPStatusError
+	require.True(t, errors.As(err, &e), "no HTTPStatusError")
+	assert.Equal(t, http.StatusNotFound, e.Code, "unexpected status")
+	assert.Equal(t, ts.URL+"/missing.html", e.URL, "unexpected URL")
+
+	// failures of other sources report the status too
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`<html><head><link rel="manifest" href="/missing.json"></head></html>`))
+	}))
+	defer ts.Close()
+
+	f = favicon.New(favicon.WithClient(ts.Client()), favicon.WithLogger(debugLogger{t}))
+	res, err := f.FindDetailed(ts.URL + "/")
+	require.Nil(t, err, "unexpected error")
+	require.True(t, errors.As(res.Err(), &e), "no HTTPStatusError")
+	assert.Equal(t, http.StatusNotFound, e.Code, "unexpected status")
+	assert.Equal(t, ts.URL+"/missing.json", e.URL, "unexpected URL")
+}
+
+// TestFailWhenEmpty verifies ErrNoIcons is returned if requested.
+func TestFailWhenEmpty(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name, path string
+		opts       []favicon.Option
+		xerr       error
+	}{
+		{"empty", "./testdata/no-markup", []favicon.Option{favicon.IgnoreManifest, favicon.IgnoreWellKnown}, nil},
+		{"fail", "./testdata/no-markup",
+			[]favicon.Option{favicon.IgnoreManifest, favicon.IgnoreWellKnown, favicon.FailWhenEmpty}, favicon.ErrNoIcons},
+		{"filtered", "./testdata/no-markup", []favicon.Option{favicon.OnlyMimeType("image/jpeg"), favicon.FailWhenEmpty}, favicon.ErrNoIcons},
+		{"found", "./testdata/no-markup", []favicon.Option{favicon.FailWhenEmpty}, nil},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			ts := httptest.NewServer(http.FileServer(http.Dir(td.path)))
+			defer ts.Close()
+
+			opts := []favicon.Option{favicon.WithClient(ts.Client()), favicon.WithLogger(debugLogger{t})}
+			f := favicon.New(append(opts, td.opts...)...)
+			icons, err := f.Find(ts.URL + "/index.html")
+			assert.True(t, errors.Is(err, td.xerr), "unexpected error: %v", err)
+
+			// the stream agrees with Find
+			s, err := f.FindStream(context.Background(), ts.URL+"/index.html")
+			require.Nil(t, err, "unexpected error")
+			var n int
+			for range s.C {
+				n++
+			}
+			assert.Equal(t, len(icons), n, "unexpected favicon count")
+			assert.True(t, errors.Is(s.Err(), td.xerr), "unexpected stream error: %v", s.Err())
+		})
+	}
+}
+
+// TestValidate verifies conflicting Options are reported.
+func TestValidate(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		opts     []favicon.Option
+		xoptions [][]string
+	}{
+		{"none", nil, nil},
+		{"compatible", []favicon.Option{favicon.OnlyPNG, favicon.OnlyMimeType("image/png", "image/jpeg"),
+			favicon.MinWidth(32), favicon.MaxWidth(32), favicon.MaxHeight(64)}, nil},
+		{"png-and-ico", []favicon.Option{favicon.OnlyPNG, favicon.OnlyICO},
+			[][]string{{`OnlyMimeType("image/png")`, `OnlyMimeType("image/x-icon", "image/vnd.microsoft.icon")`}}},
+		{"width", []favicon.Option{favicon.MinWidth(16), favicon.MinWidth(64), favicon.MaxWidth(128), favicon.MaxWidth(32)},
+			[][]string{{"MinWidth(64)", "MaxWidth(32)"}}},
+		{"multiple", []favicon.Option{favicon.OnlyPNG, favicon.OnlyICO, favicon.MinHeight(64), favicon.MaxHeight(32)},
+			[][]string{{`OnlyMimeType("image/png")`, `OnlyMimeType("image/x-icon", "image/vnd.microsoft.icon")`},
+				{"MinHeight(64)", "MaxHeight(32)"}}},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			err := favicon.New(td.opts...).Validate()
+			if td.xoptions == nil {
+				assert.Nil(t, err, "unexpected error")
+				return
+			}
+			require.NotNil(t, err, "expected error")
+
+			var joined interface{ Unwrap() []error }
+			require.True(t, errors.As(err, &joined), "not a multi-error")
+			var options [][]string
+			for _, e := range joined.Unwrap() {
+				var oe *favicon.OptionError
+				require.True(t, errors.As(e, &oe), "no OptionError")
+				options = append(options, oe.Options)
+			}
+			assert.Equal(t, td.xoptions, options, "unexpected conflicting options")
+		})
+	}
+
+	// conflicts introduced by With() don't affect the original
+	f := favicon.New(favicon.OnlyPNG)
+	require.NotNil(t, f.With(favicon.OnlyICO).Validate(), "expected error")
+	assert.Nil(t, f.Validate(), "unexpected error")
+}
+
+// TestReadError verifies errors reading a page aren't reported as
+// ErrInvalidHTML.
+func TestReadError(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		opts []favicon.Option
+	}{
+		{"default", nil},
+		{"head-only", []favicon.Option{favicon.HeadOnly}},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			r := io.MultiReader(strings.NewReader(`<html><head><title>Test</title>`), timeoutReader{})
+			_, err := htmlFinder(t, td.opts...).FindReader(r, favicon.BaseURL("https://example.com/"))
+			require.NotNil(t, err, "expected error")
+			assert.True(t, errors.Is(err, context.DeadlineExceeded), "unexpected error: %v", err)
+			assert.False(t, errors.Is(err, favicon.ErrInvalidHTML), "read error is ErrInvalidHTML")
+		})
+	}
+}
+
+// reader that fails as if its context's deadline passed.
+type timeoutReader struct{}
+
+func (timeoutReader) Read([]byte) (int, error) { return 0, context.DeadlineExceeded }
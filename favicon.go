@@ -11,15 +11,23 @@
 package favicon
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
-	"mime"
+	"net"
 	"net/http"
 	urls "net/url"
 	"path/filepath"
-
-	"github.com/pingcap/errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/html/charset"
+	"golang.org/x/net/idna"
+	"golang.org/x/text/transform"
 )
 
 // UserAgent is sent in the User-Agent HTTP header.
@@ -59,14 +67,47 @@ func WithClient(client *http.Client) Option {
 // WithFilter only returns Icons accepted by Filter functions.
 func WithFilter(filter ...Filter) Option {
 	return func(f *Finder) {
-		f.filters = append(f.filters, filter...)
+		for _, fn := range filter {
+			f.filters = append(f.filters, namedFilter{fn: fn})
+		}
 	}
 }
 
+// NamedFilter is like WithFilter, but gives the Filter a name, which is
+// logged when it rejects an Icon.
+func NamedFilter(name string, filter Filter) Option {
+	return func(f *Finder) {
+		f.filters = append(f.filters, namedFilter{name, filter})
+	}
+}
+
+// a Filter and the name it is logged under.
+type namedFilter struct {
+	name string
+	fn   Filter
+}
+
+// return the filter's name, or its position if it has none.
+func (nf namedFilter) label(i int) string {
+	if nf.name != "" {
+		return nf.name
+	}
+	return fmt.Sprintf("#%d", i+1)
+}
+
+// return strings quoted and separated by commas.
+func quoteAll(v []string) string {
+	quoted := make([]string, len(v))
+	for i, s := range v {
+		quoted[i] = strconv.Quote(s)
+	}
+	return strings.Join(quoted, ", ")
+}
+
 // OnlyMimeType only finds Icons that have one of the specified MIME types,
 // e.g. "image/png" or "image/jpeg".
 func OnlyMimeType(mimeType ...string) Option {
-	return WithFilter(func(i *Icon) *Icon {
+	filter := NamedFilter("OnlyMimeType("+quoteAll(mimeType)+")", func(i *Icon) *Icon {
 		for _, s := range mimeType {
 			if i.MimeType == s {
 				return i
@@ -74,46 +115,95 @@ func OnlyMimeType(mimeType ...string) Option {
 		}
 		return nil
 	})
+	return func(f *Finder) {
+		filter(f)
+		f.mimeTypes = append(f.mimeTypes, mimeType)
+	}
+}
+
+// OnlyPlatform only finds Icons intended for one of the specified platforms,
+// e.g. "windows" or "play". Icons that don't specify a platform are ignored.
+func OnlyPlatform(platform ...string) Option {
+	return NamedFilter("OnlyPlatform("+quoteAll(platform)+")", func(i *Icon) *Icon {
+		for _, s := range platform {
+			if i.Platform == s {
+				return i
+			}
+		}
+		return nil
+	})
+}
+
+// OnlyKind only finds Icons of the specified Kinds.
+func OnlyKind(kind ...Kind) Option {
+	names := make([]string, len(kind))
+	for i, k := range kind {
+		names[i] = string(k)
+	}
+	return NamedFilter("OnlyKind("+quoteAll(names)+")", func(i *Icon) *Icon {
+		for _, k := range kind {
+			if i.Kind == k {
+				return i
+			}
+		}
+		return nil
+	})
 }
 
 // MinWidth ignores icons smaller than the given width.
 func MinWidth(width int) Option {
-	return WithFilter(func(icon *Icon) *Icon {
+	filter := NamedFilter(fmt.Sprintf("MinWidth(%d)", width), func(icon *Icon) *Icon {
 		if icon.Width < width {
 			return nil
 		}
 		return icon
 	})
+	return func(f *Finder) {
+		filter(f)
+		f.widths = append(f.widths, sizeLimit{min: width})
+	}
 }
 
 // MaxWidth ignores icons larger than the given width.
 func MaxWidth(width int) Option {
-	return WithFilter(func(icon *Icon) *Icon {
+	filter := NamedFilter(fmt.Sprintf("MaxWidth(%d)", width), func(icon *Icon) *Icon {
 		if icon.Width > width {
 			return nil
 		}
 		return icon
 	})
+	return func(f *Finder) {
+		filter(f)
+		f.widths = append(f.widths, sizeLimit{max: width, isMax: true})
+	}
 }
 
 // MinHeight ignores icons smaller than the given height.
 func MinHeight(height int) Option {
-	return WithFilter(func(icon *Icon) *Icon {
+	filter := NamedFilter(fmt.Sprintf("MinHeight(%d)", height), func(icon *Icon) *Icon {
 		if icon.Height < height {
 			return nil
 		}
 		return icon
 	})
+	return func(f *Finder) {
+		filter(f)
+		f.heights = append(f.heights, sizeLimit{min: height})
+	}
 }
 
 // MaxHeight ignores icons larger than the given height.
 func MaxHeight(height int) Option {
-	return WithFilter(func(icon *Icon) *Icon {
+	filter := NamedFilter(fmt.Sprintf("MaxHeight(%d)", height), func(icon *Icon) *Icon {
 		if icon.Height > height {
 			return nil
 		}
 		return icon
 	})
+	return func(f *Finder) {
+		filter(f)
+		f.heights = append(f.heights, sizeLimit{max: height, isMax: true})
+	}
 }
 
 var (
@@ -121,13 +211,92 @@ var (
 	//nolint:gochecknoglobals //preset
 	IgnoreWellKnown Option = func(f *Finder) { f.ignoreWellKnown = true }
 
+	// ProbeWellKnownDir also checks locations in the /.well-known/
+	// directory, such as /.well-known/favicon.ico.
+	//nolint:gochecknoglobals //preset
+	ProbeWellKnownDir = WithWellKnownPath(wellKnownDirNames()...)
+
 	// IgnoreManifest ignores manifest.json files.
 	//nolint:gochecknoglobals //preset
 	IgnoreManifest Option = func(f *Finder) { f.ignoreManifest = true }
 
+	// IgnoreBrowserConfig ignores browserconfig.xml files.
+	//nolint:gochecknoglobals //preset
+	IgnoreBrowserConfig Option = func(f *Finder) { f.ignoreBrowserConfig = true }
+
+	// IgnoreOEmbed ignores oEmbed documents linked from the HTML page.
+	//nolint:gochecknoglobals //preset
+	IgnoreOEmbed Option = func(f *Finder) { f.ignoreOEmbed = true }
+
+	// FailWhenEmpty makes Finder return ErrNoIcons instead of an empty
+	// result if no icons are found. FindStream reports it with Stream.Err.
+	//nolint:gochecknoglobals //preset
+	FailWhenEmpty Option = func(f *Finder) { f.failWhenEmpty = true }
+
+	// Strict drops icons that can't be retrieved, whose contents aren't of
+	// their MIME type, or whose dimensions don't match their declared size.
+	// Every icon is downloaded to check it.
+	//nolint:gochecknoglobals //preset
+	Strict Option = func(f *Finder) { f.strict = true }
+
+	// Lax keeps icons without checking them. This is the default; use it to
+	// turn off Strict in a Finder derived with With().
+	//nolint:gochecknoglobals //preset
+	Lax Option = func(f *Finder) { f.strict = false }
+
+	// IncludeStartupImages also finds iOS launch screen images declared in
+	// <link rel="apple-touch-startup-image"> tags. They are large, but not
+	// really icons, so they are sorted after all other Icons.
+	//nolint:gochecknoglobals //preset
+	IncludeStartupImages Option = func(f *Finder) { f.startupImages = true }
+
+	// IncludeNoscript also finds icons declared in <noscript> elements,
+	// which some JavaScript-rendered pages wrap their <link> tags in.
+	//nolint:gochecknoglobals //preset
+	IncludeNoscript Option = func(f *Finder) { f.noscript = true }
+
+	// HeadOnly stops reading a page at the end of its <head>, which saves time
+	// and memory with large pages. Icons declared in the <body>, such as
+	// microdata, are not found, and nor are frames (see FollowFrames).
+	//nolint:gochecknoglobals //preset
+	HeadOnly Option = func(f *Finder) { f.headOnly = true }
+
+	// CanonicalHash ignores the http/https scheme and any "www." prefix of
+	// Icon URLs when calculating Icon.Hash, so the same icon reached via
+	// different URLs has the same Hash. Such Icons are also de-duplicated.
+	//nolint:gochecknoglobals //preset
+	CanonicalHash Option = func(f *Finder) { f.canonicalHash = true }
+
+	// FollowAMP also finds icons in the AMP version of a page declared with
+	// <link rel="amphtml">, or in the canonical version of an AMP page.
+	//nolint:gochecknoglobals //preset
+	FollowAMP Option = func(f *Finder) { f.followAMP = true }
+
+	// FollowFrames makes Finder search the frame's page instead if a page
+	// is only a wrapper around a single <frame> or <iframe>.
+	//nolint:gochecknoglobals //preset
+	FollowFrames Option = func(f *Finder) { f.followFrames = true }
+
+	// IncludeShortcuts also finds the icons of the app shortcuts listed in
+	// the manifest. Their Icon.Shortcut is the shortcut's name.
+	//nolint:gochecknoglobals //preset
+	IncludeShortcuts Option = func(f *Finder) { f.shortcuts = true }
+
+	// IncludeScreenshots also finds the screenshots listed in the manifest,
+	// which are sometimes a site's only large branded images. Their Kind is
+	// KindScreenshot, and like launch screens, they are sorted after all
+	// other Icons.
+	//nolint:gochecknoglobals //preset
+	IncludeScreenshots Option = func(f *Finder) { f.screenshots = true }
+
+	// IncludeAttrs populates Icon.Attrs with the attributes of the <link>
+	// element or manifest entry each Icon was read from.
+	//nolint:gochecknoglobals //preset
+	IncludeAttrs Option = func(f *Finder) { f.includeAttrs = true }
+
 	// IgnoreNoSize ignores icons with no specified size.
 	//nolint:gochecknoglobals //preset
-	IgnoreNoSize = WithFilter(func(icon *Icon) *Icon {
+	IgnoreNoSize = NamedFilter("IgnoreNoSize", func(icon *Icon) *Icon {
 		if icon.Width == 0 || icon.Height == 0 {
 			return nil
 		}
@@ -140,16 +309,31 @@ var (
 
 	// OnlyICO ignores non-ICO files.
 	//nolint:gochecknoglobals //preset
-	OnlyICO = WithFilter(func(icon *Icon) *Icon {
-		if icon.MimeType == "image/x-icon" || icon.MimeType == "image/vnd.microsoft.icon" {
-			return icon
+	OnlyICO = OnlyMimeType("image/x-icon", "image/vnd.microsoft.icon")
+
+	// OnlySiteIcons only finds favicons, ignoring touch icons, app icons,
+	// preview images etc.
+	//nolint:gochecknoglobals //preset
+	OnlySiteIcons = OnlyKind(KindSite)
+
+	// OnlyTouchIcons only finds Apple touch icons.
+	//nolint:gochecknoglobals //preset
+	OnlyTouchIcons = OnlyKind(KindTouch)
+
+	// ExcludeWellKnownProbes ignores icons that were only found by checking
+	// common locations like /favicon.ico. Unlike IgnoreWellKnown, the
+	// locations are still checked.
+	//nolint:gochecknoglobals //preset
+	ExcludeWellKnownProbes = NamedFilter("ExcludeWellKnownProbes", func(icon *Icon) *Icon {
+		if icon.Source == "well-known" {
+			return nil
 		}
-		return nil
+		return icon
 	})
 
 	// OnlySquare ignores non-square files. NOTE: Icons without a known size are also returned.
 	//nolint:gochecknoglobals //preset
-	OnlySquare = WithFilter(func(icon *Icon) *Icon {
+	OnlySquare = NamedFilter("OnlySquare", func(icon *Icon) *Icon {
 		if !icon.IsSquare() {
 			return nil
 		}
@@ -161,33 +345,84 @@ var (
 // By default, a Finder looks in the following places:
 //
 //	The HTML page at the given URL for...
-//	- icons in <link> tags
+//	- icons in <link> tags and Link response headers
 //	- Open Graph images
 //	- Twitter images
+//	- Windows tile images
+//	- schema.org microdata logos and images
+//	The AMP or canonical version of the page, with FollowAMP
 //	The manifest file...
+//	- defined in the HTML page or a Link header
+//	  -- or --
+//	- /manifest.json, /site.webmanifest or /manifest.webmanifest
+//	The browserconfig.xml file...
 //	- defined in the HTML page
 //	  -- or --
-//	- /manifest.json
+//	- /browserconfig.xml
+//	The oEmbed document linked from the HTML page (thumbnail)
+//	Any custom Sources passed to WithSource()
 //	Standard favicon paths
 //	- /favicon.ico
 //	- /apple-touch-icon.png
+//	- any passed to WithWellKnownPath() or ProbeWellKnownDir
 //
-// Pass the IgnoreManifest and/or IgnoreWellKnown Options to New() to
-// reduce the number of requests made to webservers.
+// Pass the IgnoreManifest, IgnoreBrowserConfig, IgnoreOEmbed and/or
+// IgnoreWellKnown Options to New() to reduce the number of requests made to
+// webservers.
+//
+// A Finder is not modified after it is created, so it is safe for concurrent
+// use by multiple goroutines. Use With() to derive a differently-configured
+// Finder. The Logger, Filters, Sources and AppLookups it is configured with
+// may be called concurrently, and must also be safe for concurrent use.
 type Finder struct {
-	ignoreManifest  bool
-	ignoreWellKnown bool
-	log             Logger
-	client          *http.Client
-	filters         []Filter
+	ignoreManifest      bool
+	ignoreWellKnown     bool
+	ignoreBrowserConfig bool
+	ignoreOEmbed        bool
+	failWhenEmpty       bool
+	strict              bool
+	includeAttrs        bool
+	startupImages       bool
+	noscript            bool
+	followFrames        bool
+	headOnly            bool
+	canonicalHash       bool
+	followAMP           bool
+	shortcuts           bool
+	screenshots         bool
+	lowValueCutoff      time.Duration
+	log                 Logger
+	client              *http.Client
+	filters             []namedFilter
+	sources             []Source
+	header              http.Header
+	manifestHeader      http.Header
+	manifestHosts       []string
+	pinned              []string
+	wellKnownPaths      []string
+	domains             []domainOptions
+	domainHeaders       []domainHeader
+	appStoreLookup      AppLookup
+	playStoreLookup     AppLookup
+
+	// restrictions set by filter Options, checked by Validate
+	mimeTypes       [][]string
+	widths, heights []sizeLimit
+}
+
+// a minimum or maximum set by MinWidth(), MaxHeight() etc.
+type sizeLimit struct {
+	min, max int
+	isMax    bool
 }
 
-// New creates a new Finder configured with the given options.
+// New creates a new Finder configured with the given options. Call Validate
+// on the result to check for options that conflict.
 func New(option ...Option) *Finder {
 	f := &Finder{
 		log:     nullLogger{},
 		client:  &http.Client{},
-		filters: []Filter{},
+		filters: []namedFilter{},
 	}
 	for _, fn := range option {
 		fn(f)
@@ -195,70 +430,439 @@ func New(option ...Option) *Finder {
 	return f
 }
 
-// Find finds favicons for URL.
-func (f *Finder) Find(url string) ([]*Icon, error) {
-	return f.newParser().parseURL(url)
+// With returns a copy of Finder with additional options applied. The copy
+// shares the original's HTTP client and logger unless they are overridden,
+// and Filters and Sources are added to the original's.
+func (f *Finder) With(option ...Option) *Finder {
+	ff := *f
+	ff.filters = append([]namedFilter{}, f.filters...)
+	ff.sources = append([]Source{}, f.sources...)
+	ff.header = f.header.Clone()
+	ff.manifestHeader = f.manifestHeader.Clone()
+	ff.manifestHosts = append([]string{}, f.manifestHosts...)
+	ff.pinned = append([]string{}, f.pinned...)
+	ff.wellKnownPaths = append([]string{}, f.wellKnownPaths...)
+	ff.domains = append([]domainOptions{}, f.domains...)
+	ff.domainHeaders = append([]domainHeader{}, f.domainHeaders...)
+	ff.mimeTypes = append([][]string{}, f.mimeTypes...)
+	ff.widths = append([]sizeLimit{}, f.widths...)
+	ff.heights = append([]sizeLimit{}, f.heights...)
+	for _, fn := range option {
+		fn(&ff)
+	}
+	return &ff
+}
+
+// Filters returns the names of the Finder's filters in the order they are
+// applied, e.g. "OnlyMimeType(\"image/png\")" or "MinWidth(32)". Filters
+// added with WithFilter, which have no name, are listed by position, e.g. "#3".
+func (f *Finder) Filters() []string {
+	names := make([]string, len(f.filters))
+	for i, nf := range f.filters {
+		names[i] = nf.label(i)
+	}
+	return names
+}
+
+// Validate checks Finder for Options that contradict each other, such as
+// OnlyPNG and OnlyICO, or MinWidth(64) and MaxWidth(32). Such a Finder would
+// never return any icons. The returned error joins an *OptionError for each
+// conflict; use errors.As to inspect them. Returns nil if there are none.
+func (f *Finder) Validate() error {
+	var errs []error
+	if len(f.mimeTypes) > 1 {
+		allowed := f.mimeTypes[0]
+		for _, types := range f.mimeTypes[1:] {
+			allowed = intersect(allowed, types)
+		}
+		if len(allowed) == 0 {
+			e := &OptionError{Reason: "no MIME type is allowed by all of them"}
+			for _, types := range f.mimeTypes {
+				e.Options = append(e.Options, "OnlyMimeType("+quoteAll(types)+")")
+			}
+			errs = append(errs, e)
+		}
+	}
+	if err := checkSizeLimits("Width", f.widths); err != nil {
+		errs = append(errs, err)
+	}
+	if err := checkSizeLimits("Height", f.heights); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// return an error if the largest minimum is greater than the smallest maximum.
+func checkSizeLimits(name string, limits []sizeLimit) error {
+	var lo, hi *sizeLimit
+	for i, l := range limits {
+		if l.isMax && (hi == nil || l.max < hi.max) {
+			hi = &limits[i]
+		}
+		if !l.isMax && (lo == nil || l.min > lo.min) {
+			lo = &limits[i]
+		}
+	}
+	if lo == nil || hi == nil || lo.min <= hi.max {
+		return nil
+	}
+	return &OptionError{
+		Options: []string{fmt.Sprintf("Min%s(%d)", name, lo.min), fmt.Sprintf("Max%s(%d)", name, hi.max)},
+		Reason:  "minimum is greater than maximum",
+	}
 }
 
-// FindReader finds a favicon in HTML.
-func (f *Finder) FindReader(r io.Reader, baseURL ...string) ([]*Icon, error) {
-	p := f.newParser()
-	if len(baseURL) > 0 {
-		u, err := urls.Parse(baseURL[0])
-		if err != nil {
-			return nil, errors.Wrap(err, "reader base URL")
+// return the strings in both a and b.
+func intersect(a, b []string) []string {
+	var v []string
+	for _, s := range a {
+		for _, t := range b {
+			if s == t {
+				v = append(v, s)
+				break
+			}
 		}
-		p.baseURL = u
 	}
-	return p.parseReader(r)
+	return v
+}
+
+// Result contains the Icons found for a page, along with metadata
+// discovered while parsing it.
+type Result struct {
+	Icons Icons `json:"icons"`
+	// Contents of the page's <title> element.
+	Title string `json:"title"`
+	// Value of the page's <meta name="theme-color"> tag.
+	ThemeColor string `json:"theme_color"`
+	// Absolute URL of the page's <link rel="canonical"> tag.
+	CanonicalURL string `json:"canonical_url"`
+	// Name of the app from the manifest file.
+	ManifestName string `json:"manifest_name"`
+	// Short name and colours from the manifest file. The background colour
+	// is a good choice for padding non-square icons.
+	ManifestShortName       string `json:"manifest_short_name"`
+	ManifestThemeColor      string `json:"manifest_theme_color"`
+	ManifestBackgroundColor string `json:"manifest_background_color"`
+	// URL of the manifest file that was read, whether linked from the page
+	// or found at a common location like /manifest.json, and its contents.
+	// Empty and nil if no manifest was retrieved.
+	ManifestURL string    `json:"manifest_url"`
+	Manifest    *Manifest `json:"manifest,omitempty"`
+	// Failures of individual sources, e.g. a manifest the page links to
+	// that can't be retrieved. These don't cause Find to fail, but mean the
+	// search may be incomplete. Files missing from common locations, such
+	// as /favicon.ico, aren't failures.
+	Errors FindErrors `json:"-"`
+}
+
+// Err returns Errors as an error, or nil if there were no failures.
+func (r *Result) Err() error {
+	if len(r.Errors) == 0 {
+		return nil
+	}
+	return r.Errors
+}
+
+// Find finds favicons for URL. If URL is an image rather than an HTML
+// page, the image itself is returned, along with any icons at well-known
+// locations on the same server (unless IgnoreWellKnown is set).
+func (f *Finder) Find(url string) (Icons, error) {
+	u, err := parseURLString(url)
+	if err != nil {
+		return nil, err
+	}
+	return f.FindURL(u)
+}
+
+// FindURL finds favicons for an already-parsed URL.
+func (f *Finder) FindURL(u *urls.URL) (Icons, error) {
+	r, err := f.forURL(u).newParser().parseURL(u)
+	if err != nil {
+		return nil, err
+	}
+	return r.Icons, nil
+}
+
+// FindContext finds favicons for URL like FindDetailed, stopping if ctx is
+// cancelled. See SkipLowValueWithin for making the most of a deadline.
+func (f *Finder) FindContext(ctx context.Context, url string) (*Result, error) {
+	u, err := parseURLString(url)
+	if err != nil {
+		return nil, err
+	}
+	p := f.forURL(u).newParser()
+	p.ctx = ctx
+	return p.parseURL(u)
+}
+
+// FindDetailed finds favicons for URL and also returns page metadata.
+func (f *Finder) FindDetailed(url string) (*Result, error) {
+	u, err := parseURLString(url)
+	if err != nil {
+		return nil, err
+	}
+	return f.forURL(u).newParser().parseURL(u)
+}
+
+// FindReader finds favicons in HTML read from r. See ReaderOption for
+// settings, such as the page's URL.
+func (f *Finder) FindReader(r io.Reader, option ...ReaderOption) (Icons, error) {
+	var cfg readerConfig
+	for _, fn := range option {
+		fn(&cfg)
+	}
+
+	var u *urls.URL
+	if cfg.baseURL != "" {
+		var err error
+		if u, err = urls.Parse(cfg.baseURL); err != nil {
+			return nil, fmt.Errorf("reader base URL: %w", err)
+		}
+		u.Fragment, u.RawFragment = "", ""
+	}
+	if cfg.charset != "" {
+		enc, _ := charset.Lookup(cfg.charset)
+		if enc == nil {
+			return nil, fmt.Errorf("unknown charset: %q", cfg.charset)
+		}
+		r = transform.NewReader(r, enc.NewDecoder())
+	}
+	if len(cfg.options) > 0 {
+		f = f.With(cfg.options...)
+	}
+
+	p := f.forURL(u).newParser()
+	p.baseURL = u
+	res, err := p.parseReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return res.Icons, nil
+}
+
+// FindBytes finds favicons in raw HTML. Unlike FindReader, which assumes
+// UTF-8, FindBytes determines the encoding from a byte-order mark or
+// <meta> charset declaration and decodes the HTML before parsing it.
+// Options are the same as FindReader's; Charset overrides detection.
+func (f *Finder) FindBytes(b []byte, option ...ReaderOption) (Icons, error) {
+	_, name, _ := charset.DetermineEncoding(b, "text/html")
+	f.log.Printf("detected charset %q", name)
+	option = append([]ReaderOption{Charset(name)}, option...)
+	return f.FindReader(bytes.NewReader(b), option...)
+}
+
+// FindFile finds favicons for a local HTML file. The manifest and well-known
+// files are read from the same directory tree instead of over HTTP, with
+// root-relative URLs like /favicon.ico resolved against siteRoot. If siteRoot
+// is not given, the file's directory is used.
+//
+// Icon URLs are file:// URLs relative to siteRoot, e.g. file:///favicon.ico.
+func (f *Finder) FindFile(path string, siteRoot ...string) (Icons, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path: %w", err)
+	}
+	root := filepath.Dir(path)
+	if len(siteRoot) > 0 {
+		if root, err = filepath.Abs(siteRoot[0]); err != nil {
+			return nil, fmt.Errorf("invalid site root: %w", err)
+		}
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not in site root %q", path, root)
+	}
+	// file names may contain "%" or "#", so don't build the URL as a string
+	rel = filepath.ToSlash(rel)
+	if rel == ".." || strings.HasPrefix(rel, "../") {
+		return nil, fmt.Errorf("%q is not in site root %q", path, root)
+	}
+
+	ff := f.With(WithClient(&http.Client{Transport: http.NewFileTransport(http.Dir(root))}))
+	return ff.FindURL(&urls.URL{Scheme: "file", Path: "/" + rel})
+}
+
+// FindWellKnown only checks common locations like /favicon.ico on the server
+// at baseURL. The HTML page and manifest are not retrieved.
+func (f *Finder) FindWellKnown(baseURL string) (Icons, error) {
+	u, err := parseURLString(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("not an absolute URL: %q", baseURL)
+	}
+	p := f.forURL(u).newParser()
+	p.baseURL = u
+	icons := p.postProcessIcons(p.findWellKnownIcons())
+	if p.find.failWhenEmpty && len(icons) == 0 {
+		return nil, ErrNoIcons
+	}
+	return icons, nil
 }
 
 // Retrieve a URL and return response body. Returns an error if response status >= 300.
-func (f *Finder) fetchURL(url string) (io.ReadCloser, error) {
-	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, nil)
+func (f *Finder) fetchURL(ctx context.Context, url string) (io.ReadCloser, error) {
+	resp, err := f.fetch(ctx, url)
 	if err != nil {
-		return nil, errors.Wrap(err, "request URL")
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Retrieve a URL and return the response. Returns an error if response status >= 300.
+// The caller must close the response body.
+func (f *Finder) fetch(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("request URL: %w", err)
+	}
+	if header := f.headerFor(req.URL.Hostname()); header != nil {
+		req.Header = header
 	}
 	req.Header.Set("User-Agent", UserAgent)
 
-	resp, err := f.client.Do(req)
+	resp, err := f.loopCheckingClient().Do(req)
 	if err != nil {
-		return nil, errors.Wrap(err, "retrieve URL")
+		return nil, fmt.Errorf("retrieve URL: %w", err)
 	}
 	f.log.Printf("[%d] %s", resp.StatusCode, url)
 
 	if resp.StatusCode != http.StatusOK {
 		_ = resp.Body.Close()
-		return nil, fmt.Errorf("[%d] %s", resp.StatusCode, resp.Status)
+		return nil, &HTTPStatusError{Code: resp.StatusCode, URL: url}
 	}
 
-	return resp.Body, nil
+	return resp, nil
 }
 
-type parser struct {
-	baseURL *urls.URL
-	charset string
+// maximum number of HTTP redirects followed; the same as http.Client's default.
+const maxHTTPRedirects = 10
+
+// return a copy of Finder's client that fails with a *RedirectLoopError if
+// redirects lead back to an already-requested URL. The client's own
+// CheckRedirect is called for other redirects.
+func (f *Finder) loopCheckingClient() *http.Client {
+	client := *f.client
+	check := client.CheckRedirect
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		visited := make([]string, len(via))
+		for i, r := range via {
+			visited[i] = r.URL.String()
+		}
+		if err := checkLoop(req.URL.String(), visited); err != nil {
+			return err
+		}
+		// don't forward a domain's headers to another host
+		header := f.headerFor(req.URL.Hostname())
+		for _, d := range f.domainHeaders {
+			for k := range d.header {
+				if v := header.Values(k); len(v) > 0 {
+					req.Header[k] = v
+				} else {
+					req.Header.Del(k)
+				}
+			}
+		}
+		if check != nil {
+			return check(req, via)
+		}
+		if len(via) >= maxHTTPRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxHTTPRedirects)
+		}
+		return nil
+	}
+	return &client
+}
 
+type parser struct {
+	baseURL        *urls.URL
+	docBase        *urls.URL // from <base href>; overrides baseURL for relative URLs
+	charset        string
+	appStoreID     string   // from apple-itunes-app meta tag
+	configURL      string   // from msapplication-config meta tag; empty if disabled
+	configDeclared bool     // configURL is from the meta tag, not the default
+	oembedURL      string   // from <link rel="alternate" type="application/json+oembed">
+	links          []string // Link headers of the page response
+	useCreds       bool     // manifest link has crossorigin="use-credentials"
+	image          *Icon    // set if the URL is an image, not a page
+	result         Result
+	mu             sync.Mutex // protects result fields set by concurrent sources
+
+	ctx  context.Context
 	find *Finder
 }
 
 func (f *Finder) newParser() *parser {
-	return &parser{find: f}
+	return &parser{ctx: context.Background(), find: f}
 }
 
 func (p *parser) absURL(url string) string {
-	if url == "" || p.baseURL == nil {
+	base := p.baseURL
+	if p.docBase != nil {
+		base = p.docBase
+	}
+	return resolveURL(base, url)
+}
+
+// resolve url relative to base. Returns "" if url is invalid. As in
+// browsers, surrounding whitespace is ignored. The result is normalised with
+// normalizeURL, so the same icon always has the same URL.
+func resolveURL(base *urls.URL, url string) string {
+	url = strings.TrimSpace(url)
+	if url == "" || base == nil || isDataURL(url) {
 		return url
 	}
 
-	u, err := urls.Parse(url)
+	ref, err := urls.Parse(url)
 	if err != nil {
 		return ""
 	}
-	if p.baseURL != nil {
-		return p.baseURL.ResolveReference(u).String()
+	u := base.ResolveReference(ref)
+	normalizeURL(u)
+	return u.String()
+}
+
+// convert an internationalised host name in u to ASCII (punycode), which
+// net/url would otherwise percent-encode, and upper-case the hex digits of
+// percent-encoded bytes in its path and query (RFC 3986 section 6.2.2.1).
+func normalizeURL(u *urls.URL) {
+	if host := u.Hostname(); host != "" {
+		if ascii, err := idna.Lookup.ToASCII(host); err == nil && ascii != host {
+			if port := u.Port(); port != "" {
+				ascii = net.JoinHostPort(ascii, port)
+			}
+			u.Host = ascii
+		}
+	}
+	u.RawPath = upperEscapes(u.RawPath)
+	u.RawQuery = upperEscapes(u.RawQuery)
+}
+
+// return s with the hex digits of percent-encoded bytes in upper case.
+func upperEscapes(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+	b := []byte(s)
+	for i := 0; i+2 < len(b); i++ {
+		if b[i] == '%' && isHex(b[i+1]) && isHex(b[i+2]) {
+			b[i+1], b[i+2] = upperHex(b[i+1]), upperHex(b[i+2])
+			i += 2
+		}
+	}
+	return string(b)
+}
+
+func isHex(c byte) bool {
+	return ('0' <= c && c <= '9') || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+}
+
+func upperHex(c byte) byte {
+	if 'a' <= c && c <= 'f' {
+		return c - 'a' + 'A'
 	}
-	return url
+	return c
 }
 
 // return MIME type based on file extension in URL.
@@ -267,5 +871,5 @@ func mimeTypeURL(url string) string {
 	if err != nil {
 		return ""
 	}
-	return mime.TypeByExtension(filepath.Ext(u.Path))
+	return mimeTypeExt(filepath.Ext(u.Path))
 }
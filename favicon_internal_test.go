@@ -1,6 +1,10 @@
 package favicon
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
 	urls "net/url"
 	"os"
 	"testing"
@@ -29,7 +33,8 @@ func TestFindManifest(t *testing.T) {
 	p := f.newParser()
 	p.baseURL = mustURL("https://github.com")
 
-	icons := p.parseManifestReader(file)
+	icons, err := p.parseManifestReader(file, "")
+	require.Nil(t, err, "unexpected error")
 	assert.Equal(t, 11, len(icons), "unexpected favicon count")
 }
 
@@ -69,3 +74,313 @@ func mustURL(s string) *urls.URL {
 	}
 	return u
 }
+
+// TestRegisterMimeType tests custom extension mappings.
+func TestRegisterMimeType(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "", mimeTypeURL("https://example.com/icon.fvtest"), "unexpected MIME type")
+
+	RegisterMimeType("FVTEST", "image/x-favicon-test")
+	tests := []struct {
+		name, in, x string
+	}{
+		{"lowercase", "https://example.com/icon.fvtest", "image/x-favicon-test"},
+		{"uppercase", "https://example.com/icon.FVTest", "image/x-favicon-test"},
+		{"query", "https://example.com/icon.fvtest?v=2", "image/x-favicon-test"},
+		{"other", "https://example.com/icon.fvtest2", ""},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, td.x, mimeTypeURL(td.in), "unexpected MIME type")
+		})
+	}
+}
+
+// TestParseLinkHeader tests parsing of HTTP Link headers.
+func TestParseLinkHeader(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		in   []string
+		x    []linkHeader
+	}{
+		{"empty", nil, nil},
+		{"simple", []string{`</favicon.svg>; rel="icon"`},
+			[]linkHeader{{"/favicon.svg", map[string]string{"rel": "icon"}}}},
+		{"params", []string{`<https://cdn.example.com/a.png> ; REL=icon; sizes="16x16 32x32";type=image/png`},
+			[]linkHeader{{"https://cdn.example.com/a.png", map[string]string{
+				"rel": "icon", "sizes": "16x16 32x32", "type": "image/png",
+			}}}},
+		{"multiple", []string{`</a.css>; rel=preload; as=style, </b.ico>; rel="shortcut icon"`, `</c.png>;rel=apple-touch-icon`},
+			[]linkHeader{
+				{"/a.css", map[string]string{"rel": "preload", "as": "style"}},
+				{"/b.ico", map[string]string{"rel": "shortcut icon"}},
+				{"/c.png", map[string]string{"rel": "apple-touch-icon"}},
+			}},
+		{"quoted", []string{`</a.png>; title="a, \"b\"; c"; rel=icon; rel=other`},
+			[]linkHeader{{"/a.png", map[string]string{"title": `a, "b"; c`, "rel": "icon"}}}},
+		{"flag", []string{`</a.png>; crossorigin; rel=icon`},
+			[]linkHeader{{"/a.png", map[string]string{"crossorigin": "", "rel": "icon"}}}},
+		{"invalid", []string{`/a.png; rel=icon`, `</b.png; rel=icon`}, nil},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, td.x, parseLinkHeader(td.in), "unexpected links")
+		})
+	}
+}
+
+// TestLinkHeaderKind tests the classification of Link header relation types.
+func TestLinkHeaderKind(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		rel string
+		x   Kind
+	}{
+		{"", ""},
+		{"preload", ""},
+		{"icon", KindSite},
+		{"shortcut icon", KindSite},
+		{"Preload  ICON", KindSite},
+		{"mask-icon", KindSite},
+		{"icon apple-touch-icon", KindTouch},
+		{"apple-touch-icon-precomposed", KindTouch},
+		{"fluid-icon", KindApp},
+		{"manifest", ""},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.rel, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, td.x, linkHeaderKind(td.rel), "unexpected kind")
+		})
+	}
+}
+
+// TestCanonicalURL tests normalisation of URLs for CanonicalHash.
+func TestCanonicalURL(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name, in, x string
+	}{
+		{"https", "https://example.com/favicon.ico", "//example.com/favicon.ico"},
+		{"http", "http://example.com/favicon.ico", "//example.com/favicon.ico"},
+		{"www", "https://WWW.Example.com/favicon.ico?v=2", "//example.com/favicon.ico?v=2"},
+		{"defaultPort", "http://example.com:80/favicon.ico", "//example.com/favicon.ico"},
+		{"port", "https://example.com:8443/favicon.ico", "//example.com:8443/favicon.ico"},
+		{"httpPort", "https://example.com:80/favicon.ico", "//example.com:80/favicon.ico"},
+		{"httpsPort", "http://example.com:443/favicon.ico", "//example.com:443/favicon.ico"},
+		{"punycode", "https://xn--mnchen-3ya.example/favicon.ico", "//xn--mnchen-3ya.example/favicon.ico"},
+		{"subdomain", "https://cdn.example.com/favicon.ico", "//cdn.example.com/favicon.ico"},
+		{"file", "file:///favicon.ico", "file:///favicon.ico"},
+		{"data", "data:image/png;base64,AAAA", "data:image/png;base64,AAAA"},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, td.x, canonicalURL(td.in), "unexpected URL")
+		})
+	}
+}
+
+// TestResolveURL tests absolutisation and normalisation of exotic URLs.
+func TestResolveURL(t *testing.T) {
+	t.Parallel()
+	base, err := urls.Parse("https://example.com:8443/dir/")
+	require.Nil(t, err, "unexpected error")
+	tests := []struct {
+		name, in, x string
+	}{
+		{"relative", "icon.png", "https://example.com:8443/dir/icon.png"},
+		{"whitespace", " \n/icon.png\t", "https://example.com:8443/icon.png"},
+		{"space", "my icon.png", "https://example.com:8443/dir/my%20icon.png"},
+		{"non-ASCII", "/图标.png", "https://example.com:8443/%E5%9B%BE%E6%A0%87.png"},
+		{"lowercase escapes", "/%e5%9b%be%e6%a0%87.png?q=%c3%a9", "https://example.com:8443/%E5%9B%BE%E6%A0%87.png?q=%C3%A9"},
+		{"escaped slash", "/a%2fb.png", "https://example.com:8443/a%2Fb.png"},
+		{"IDN", "https://münchen.example/icon.png", "https://xn--mnchen-3ya.example/icon.png"},
+		{"IDN port", "//例子.example:8080/icon.png", "https://xn--fsqu00a.example:8080/icon.png"},
+		{"escaped IDN", "https://m%C3%BCnchen.example/icon.png", "https://xn--mnchen-3ya.example/icon.png"},
+		{"IPv6", "http://[::1]:8080/icon.png", "http://[::1]:8080/icon.png"},
+		{"data", "data:image/png;base64,AAAA", "data:image/png;base64,AAAA"},
+		{"invalid", "/icon%zz.png", ""},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, td.x, resolveURL(base, td.in), "unexpected URL")
+		})
+	}
+}
+
+// fuzzFinder returns a Finder that only parses its input and makes no
+// HTTP requests.
+func fuzzFinder() *Finder {
+	return New(IgnoreManifest, IgnoreWellKnown, IgnoreBrowserConfig, IgnoreOEmbed,
+		IncludeNoscript, IncludeStartupImages, FollowFrames,
+		WithClient(&http.Client{Transport: offlineTransport{}}))
+}
+
+// transport that refuses all requests, so fuzzed redirects and frames
+// don't hit the network.
+type offlineTransport struct{}
+
+func (offlineTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errors.New("offline")
+}
+
+// report icons with negative sizes or values that can't be encoded as JSON,
+// e.g. a NaN Scale.
+func checkIconValues(t *testing.T, icons []*Icon) {
+	t.Helper()
+	for _, icon := range icons {
+		if icon.Width < 0 || icon.Height < 0 {
+			t.Errorf("negative size: %#v", icon)
+		}
+	}
+	if _, err := json.Marshal(icons); err != nil {
+		t.Errorf("encode icons: %v", err)
+	}
+}
+
+// FuzzFindReader checks that arbitrary HTML doesn't crash the extractor and
+// only yields valid icons.
+func FuzzFindReader(f *testing.F) {
+	// keep seeds small: the fuzzer mutates whole inputs, and a large page
+	// slows every execution
+	data, err := os.ReadFile("testdata/kuli/index.html")
+	require.Nil(f, err, "unexpected error")
+	f.Add(data)
+	f.Add([]byte(`<head><link rel="mask-icon" href="/pinned.svg" color="#000">` +
+		`<link rel="alternate icon" type="image/png" href="/favicon.png">` +
+		`<link rel="manifest" href="/manifest.json" crossorigin="use-credentials">` +
+		`<meta name="msapplication-TileImage" content="/tile.png"></head>`))
+	f.Add([]byte(`<link rel="icon" sizes="16x16 32x32" href="data:image/png;base64,iVBORw0KGgo=">`))
+	f.Add([]byte(`<base href="//x"><link rel="apple-touch-icon" href="a-180.png" media="(min-width: 0)">`))
+	f.Add([]byte(`<meta property="og:image" content="/og.png"><meta property="og:image:width" content="-1">`))
+	f.Add([]byte(`<link rel="apple-touch-startup-image" href="/a.png" ` +
+		`media="(device-width: 99999999999999999999px) and (device-height: 812px)">`))
+	f.Add([]byte(`<link rel="apple-touch-startup-image" href="/a.png" ` +
+		`media="(device-width: 375px) and (device-height: 812px) and (device-pixel-ratio: 1e308)">`))
+
+	fnd := fuzzFinder()
+	f.Fuzz(func(t *testing.T, data []byte) {
+		icons, err := fnd.FindBytes(data, BaseURL("https://example.com/dir/"))
+		if err != nil {
+			return
+		}
+		for _, icon := range icons {
+			if icon.URL == "" || icon.MimeType == "" || icon.Hash == "" {
+				t.Errorf("invalid icon: %#v", icon)
+			}
+		}
+		checkIconValues(t, icons)
+	})
+}
+
+// FuzzParseManifest checks that arbitrary manifests don't crash the parser.
+func FuzzParseManifest(f *testing.F) {
+	data, err := os.ReadFile("testdata/github/manifest.json")
+	require.Nil(f, err, "unexpected error")
+	f.Add(data)
+	f.Add([]byte(`{"icons": {"16": "a.png", "x": 1}}`))
+	f.Add([]byte(`{"icons": {"url": "a.png", "sizes": 192, "density": "2"}}`))
+	f.Add([]byte(`{"icons": [{"src": "a", "sizes": ["1x1", 0, -1]}], "shortcuts": [{"icons": null}]}`))
+	f.Add([]byte(`{"icons": [{"src": "a.png", "density": 1e300}, {"src": "b.png", "density": "Inf"}]}`))
+	f.Add([]byte(`{"icons": [{"src": "a.png", "sizes": "192x192", "density": "NaN"}]}`))
+
+	fnd := New(IncludeShortcuts, IncludeAttrs)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		p := fnd.newParser()
+		p.baseURL = mustURL("https://example.com/")
+		icons, _ := p.parseManifestReader(bytes.NewReader(data), "https://example.com/static/manifest.json")
+		for _, icon := range icons {
+			if icon.URL == "" {
+				t.Errorf("invalid icon: %#v", icon)
+			}
+		}
+		checkIconValues(t, icons)
+	})
+}
+
+// FuzzParseOEmbed checks that arbitrary oEmbed documents don't crash the
+// parser or yield invalid sizes.
+func FuzzParseOEmbed(f *testing.F) {
+	data, err := os.ReadFile("testdata/oembed/oembed.json")
+	require.Nil(f, err, "unexpected error")
+	f.Add(data)
+	f.Add([]byte(`{"thumbnail_url": "/a.jpg", "thumbnail_width": 1e300, "thumbnail_height": "NaN"}`))
+
+	fnd := New()
+	f.Fuzz(func(t *testing.T, data []byte) {
+		p := fnd.newParser()
+		p.baseURL = mustURL("https://example.com/")
+		icons, _ := p.parseOEmbedReader(bytes.NewReader(data))
+		checkIconValues(t, icons)
+	})
+}
+
+// FuzzParseSizes checks that sizes parsed from attributes and URLs are
+// never negative.
+func FuzzParseSizes(f *testing.F) {
+	for _, s := range []string{
+		"16x16", "16X16 32x32", "any", "99999999999x1", "/icons/icon-192.png",
+		"https://example.com/a-512x512.png?v=1", "/-.png", "%zz-1",
+	} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		for _, sz := range parseSizes(s) {
+			if sz.w < 0 || sz.h < 0 {
+				t.Errorf("negative size %dx%d from %q", sz.w, sz.h, s)
+			}
+		}
+		if sz := extractSizeFromURL(s); sz != nil && (sz.w < 0 || sz.h < 0) {
+			t.Errorf("negative size %dx%d from URL %q", sz.w, sz.h, s)
+		}
+	})
+}
+
+// FuzzParseLinkHeader checks that arbitrary Link headers don't crash the
+// parser and yield no links without URLs.
+func FuzzParseLinkHeader(f *testing.F) {
+	f.Add(`</a.css>; rel=preload; as=style, </b.ico>; rel="shortcut icon"`)
+	f.Add(`</a.png>; title="a, \"b\"; c"; rel=icon; rel=other`)
+	f.Add(`<`)
+	f.Add(`</a>;"`)
+	f.Fuzz(func(t *testing.T, s string) {
+		for _, link := range parseLinkHeader([]string{s}) {
+			if link.Params == nil {
+				t.Errorf("nil params for %q", s)
+			}
+		}
+	})
+}
+
+// FuzzParseDataURL checks that arbitrary data: URLs don't crash the parser
+// or the image size detection.
+func FuzzParseDataURL(f *testing.F) {
+	f.Add("data:image/png;base64,iVBORw0KGgo=")
+	f.Add("data:image/svg+xml,%3Csvg%2F%3E")
+	f.Add("data:;base64,AAABAAEAEBAAAAEAIABoBAAAFgAAAA==")
+	f.Add("data:,")
+	f.Fuzz(func(t *testing.T, s string) {
+		_, data, err := parseDataURL(s)
+		if err != nil {
+			return
+		}
+		if w, h, ok := imageSize(data); ok && (w <= 0 || h <= 0) {
+			t.Errorf("invalid size %dx%d", w, h)
+		}
+	})
+}
@@ -5,12 +5,20 @@
 package favicon_test
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	urls "net/url"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/muzhou233/go-favicon"
+	"github.com/muzhou233/go-favicon/favicontest"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -24,6 +32,19 @@ func (l debugLogger) Printf(format string, v ...interface{}) {
 	l.t.Logf(format, v...)
 }
 
+// returns a Finder that only reads HTML and doesn't make any HTTP requests.
+func htmlFinder(t *testing.T, opts ...favicon.Option) *favicon.Finder {
+	t.Helper()
+	opts = append(opts,
+		favicon.WithLogger(debugLogger{t}),
+		favicon.IgnoreManifest,
+		favicon.IgnoreWellKnown,
+		favicon.IgnoreBrowserConfig,
+		favicon.IgnoreOEmbed,
+	)
+	return favicon.New(opts...)
+}
+
 // TestBaseURL verifies absolute links.
 func TestBaseURL(t *testing.T) {
 	t.Parallel()
@@ -36,6 +57,7 @@ func TestBaseURL(t *testing.T) {
 		favicon.OnlyICO,
 		favicon.IgnoreWellKnown,
 		favicon.IgnoreManifest,
+		favicon.IgnoreBrowserConfig,
 	)
 	require.Nil(t, err, "unexpected error")
 
@@ -44,7 +66,7 @@ func TestBaseURL(t *testing.T) {
 		x       = "https://www.kulturliste-duesseldorf.de/favicon-rot.ico"
 		icons   []*favicon.Icon
 	)
-	icons, err = f.FindReader(file, baseURL)
+	icons, err = f.FindReader(file, favicon.BaseURL(baseURL))
 	require.Nil(t, err, "unexpected error")
 	// for _, i := range icons {
 	// 	fmt.Println(i)
@@ -53,6 +75,42 @@ func TestBaseURL(t *testing.T) {
 	assert.Equal(t, x, icons[0].URL, "unexpected favicon URL")
 }
 
+// TestBaseElement verifies relative URLs are resolved against <base href>.
+func TestBaseElement(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name, base, pageURL string
+		x                   []string
+	}{
+		{"none", "", "https://example.com/proxy/page.html",
+			[]string{"https://example.com/proxy/icons/icon.png", "https://example.com/favicon.ico"}},
+		{"relative", `<base href="/app/">`, "https://example.com/proxy/page.html",
+			[]string{"https://example.com/app/icons/icon.png", "https://example.com/favicon.ico"}},
+		{"absolute", `<base href="https://cdn.example.com/assets/">`, "https://example.com/",
+			[]string{"https://cdn.example.com/assets/icons/icon.png", "https://cdn.example.com/favicon.ico"}},
+		{"no-page-url", `<base href="https://cdn.example.com/assets/">`, "",
+			[]string{"https://cdn.example.com/assets/icons/icon.png", "https://cdn.example.com/favicon.ico"}},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			html := `<html><head>` + td.base + `
+				<link rel="icon" type="image/png" href="icons/icon.png">
+				<link rel="icon" type="image/x-icon" href="/favicon.ico">
+			</head></html>`
+			icons, err := htmlFinder(t).FindReader(strings.NewReader(html), favicon.BaseURL(td.pageURL))
+			require.Nil(t, err, "unexpected error")
+			var found []string
+			for _, icon := range icons {
+				found = append(found, icon.URL)
+			}
+			assert.Equal(t, td.x, found, "unexpected favicon URLs")
+		})
+	}
+}
+
 // TestFindHTML parses HTML only.
 func TestFindHTML(t *testing.T) {
 	t.Parallel()
@@ -66,7 +124,103 @@ func TestFindHTML(t *testing.T) {
 	var icons []*favicon.Icon
 	icons, err = f.FindReader(file)
 	require.Nil(t, err, "unexpected error")
-	assert.Equal(t, 6, len(icons), "unexpected favicon count")
+	assert.Equal(t, 7, len(icons), "unexpected favicon count")
+}
+
+// TestFindBytes verifies that non-UTF-8 HTML is decoded.
+func TestFindBytes(t *testing.T) {
+	t.Parallel()
+	const baseURL = "https://example.com/"
+	x := baseURL + "%E3%82%A2%E3%82%A4%E3%82%B3%E3%83%B3-32x32.png"
+	for _, name := range []string{"utf-8", "shift_jis", "gbk"} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			data, err := os.ReadFile("testdata/charset/" + name + ".html")
+			require.Nil(t, err, "unexpected error")
+
+			icons, err := htmlFinder(t).FindBytes(data, favicon.BaseURL(baseURL))
+			require.Nil(t, err, "unexpected error")
+			require.Equal(t, 1, len(icons), "unexpected favicon count")
+			assert.Equal(t, x, icons[0].URL, "unexpected favicon URL")
+		})
+	}
+}
+
+// TestFetchCharset verifies retrieved pages are decoded according to their
+// Content-Type header or <meta> charset declaration.
+func TestFetchCharset(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := os.ReadFile("testdata/charset" + r.URL.Path)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", r.URL.Query().Get("type"))
+		_, _ = w.Write(data)
+	}))
+	t.Cleanup(ts.Close)
+
+	tests := []struct {
+		name, contentType string
+		x                 string // title
+	}{
+		{"utf-8", "text/html", "文字コード"},
+		{"shift_jis", "text/html", "文字コード"},
+		{"gbk", "text/html; charset=GBK", "文字コード"},
+		{"euc-kr", "text/html; charset=euc-kr", "문자 코드"},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			f := favicon.New(
+				favicon.WithClient(ts.Client()),
+				favicon.WithLogger(debugLogger{t}),
+				favicon.IgnoreManifest,
+				favicon.IgnoreWellKnown,
+				favicon.IgnoreBrowserConfig,
+			)
+			res, err := f.FindDetailed(ts.URL + "/" + td.name + ".html?type=" + urls.QueryEscape(td.contentType))
+			require.Nil(t, err, "unexpected error")
+			assert.Equal(t, td.x, res.Title, "unexpected title")
+			require.Equal(t, 1, len(res.Icons), "unexpected favicon count")
+			assert.Equal(t, ts.URL+"/%E3%82%A2%E3%82%A4%E3%82%B3%E3%83%B3-32x32.png", res.Icons[0].URL, "unexpected favicon URL")
+		})
+	}
+}
+
+// TestLegacyRels verifies icons are read from precomposed, Fluid and
+// image_src links, and that image_src links are sorted last.
+func TestLegacyRels(t *testing.T) {
+	t.Parallel()
+	file, err := os.Open("testdata/legacy/index.html")
+	require.Nil(t, err, "unexpected error")
+	defer file.Close()
+
+	icons, err := htmlFinder(t).FindReader(file, favicon.BaseURL("https://example.com/"))
+	require.Nil(t, err, "unexpected error")
+	var found []string
+	for _, icon := range icons {
+		found = append(found, icon.URL)
+	}
+	assert.ElementsMatch(t, []string{
+		"https://example.com/apple-touch-icon-114x114-precomposed.png",
+		"https://example.com/apple-touch-icon-precomposed.png",
+		"https://example.com/fluidicon.png",
+		"https://example.com/share-1200x630.png",
+	}, found, "unexpected favicon URLs")
+
+	last := icons[len(icons)-1]
+	assert.Equal(t, "https://example.com/share-1200x630.png", last.URL, "image_src not last")
+	assert.Equal(t, 1200, last.Width, "unexpected width")
+	assert.Negative(t, last.Rank, "unexpected rank")
+	// Rank doesn't affect size helpers
+	assert.Equal(t, last, icons.Largest(), "image_src not largest")
+	assert.Equal(t, "https://example.com/apple-touch-icon-114x114-precomposed.png", icons.Smallest().URL,
+		"unexpected smallest icon")
 }
 
 // TestHTTP tests fetching via HTTP.
@@ -76,8 +230,8 @@ func TestHTTP(t *testing.T) {
 		name, path string
 		xcount     int
 	}{
-		{"github", "./testdata/github", 17},
-		{"kuli", "./testdata/kuli", 7},
+		{"github", "./testdata/github", 18},
+		{"kuli", "./testdata/kuli", 8},
 		{"mozilla", "./testdata/mozilla", 4},
 		{"no-markup", "./testdata/no-markup", 3},
 	}
@@ -93,6 +247,208 @@ func TestHTTP(t *testing.T) {
 			icons, err := f.Find(ts.URL + "/index.html")
 			require.Nil(t, err, "unexpected error")
 			assert.Equal(t, td.xcount, len(icons), "unexpected favicon count")
+
+			u, err := urls.Parse(ts.URL + "/index.html")
+			require.Nil(t, err, "unexpected error")
+			icons2, err := f.FindURL(u)
+			require.Nil(t, err, "unexpected error")
+			assert.Equal(t, icons, icons2, "FindURL differs from Find")
+		})
+	}
+}
+
+// TestGolden compares the icons found in sample sites to golden files in
+// testdata/golden. Set FAVICON_UPDATE_GOLDEN=1 to update them.
+func TestGolden(t *testing.T) {
+	t.Parallel()
+	for _, name := range []string{"github", "kuli", "mozilla", "no-markup"} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			ts := httptest.NewServer(http.FileServer(http.Dir("./testdata/" + name)))
+			defer ts.Close()
+
+			f := favicon.New(favicon.WithClient(ts.Client()), favicon.WithLogger(debugLogger{t}))
+			icons, err := f.Find(ts.URL + "/index.html")
+			require.Nil(t, err, "unexpected error")
+			favicontest.Check(t, "./testdata/golden/"+name+".golden", icons, ts.URL)
+		})
+	}
+}
+
+// TestFindFile tests reading a site from the filesystem.
+func TestFindFile(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name, path, root string
+		xcount           int
+	}{
+		{"github", "./testdata/github/index.html", "", 18},
+		{"kuli", "./testdata/kuli/index.html", "", 8},
+		{"mozilla", "./testdata/mozilla/index.html", "", 4},
+		{"no-markup", "./testdata/no-markup/index.html", "", 3},
+		{"no-markup-root", "./testdata/no-markup/index.html", "./testdata/no-markup", 3},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			var roots []string
+			if td.root != "" {
+				roots = append(roots, td.root)
+			}
+			f := favicon.New(favicon.WithLogger(debugLogger{t}))
+			icons, err := f.FindFile(td.path, roots...)
+			require.Nil(t, err, "unexpected error")
+			assert.Equal(t, td.xcount, len(icons), "unexpected favicon count")
+		})
+	}
+
+	_, err := favicon.New().FindFile("./testdata/kuli/index.html", "./testdata/github")
+	assert.NotNil(t, err, "expected error for file outside root")
+
+	// names that aren't valid in URLs
+	dir := t.TempDir()
+	for _, name := range []string{"100% off.html", "a#b.html", "..foo.html"} {
+		path := filepath.Join(dir, name)
+		require.Nil(t, os.WriteFile(path, []byte(`<link rel="icon" href="icon.png">`), 0o600), "unexpected error")
+		icons, err := htmlFinder(t).FindFile(path)
+		require.Nil(t, err, "unexpected error for "+name)
+		require.Equal(t, 1, len(icons), "unexpected favicon count for "+name)
+		assert.Equal(t, "file:///icon.png", icons[0].URL, "unexpected URL for "+name)
+	}
+}
+
+// TestFindDetailed verifies page metadata.
+func TestFindDetailed(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name, path   string
+		title        string
+		themeColor   string
+		canonicalURL string
+		manifestName string
+	}{
+		{"github", "./testdata/github", "GitHub", "#1e2327", "", "GitHub"},
+		{"kuli", "./testdata/kuli", "Kulturliste Düsseldorf", "#fcc004", "", "Kulturliste Düsseldorf"},
+		{"mozilla", "./testdata/mozilla",
+			"Security Vulnerabilities fixed in Firefox 82.0.3 and Firefox ESR 78.4.1 — Mozilla", "",
+			"https://www.mozilla.org/en-US/security/advisories/mfsa2020-49/", ""},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			ts := httptest.NewServer(http.FileServer(http.Dir(td.path)))
+			defer ts.Close()
+
+			f := favicon.New(favicon.WithClient(ts.Client()), favicon.WithLogger(debugLogger{t}))
+			res, err := f.FindDetailed(ts.URL + "/index.html")
+			require.Nil(t, err, "unexpected error")
+			assert.Equal(t, td.title, res.Title, "unexpected title")
+			assert.Equal(t, td.themeColor, res.ThemeColor, "unexpected theme colour")
+			assert.Equal(t, td.canonicalURL, res.CanonicalURL, "unexpected canonical URL")
+			assert.Equal(t, td.manifestName, res.ManifestName, "unexpected manifest name")
+			assert.NotEmpty(t, res.Icons, "no icons found")
+		})
+	}
+}
+
+// TestFindWellKnown verifies probing of common locations without HTML.
+func TestFindWellKnown(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name, path string
+		opts       []favicon.Option
+		x          []string // paths
+	}{
+		{"github", "./testdata/github", nil, nil},
+		{"multisize", "./testdata/multisize", nil, []string{"/favicon.ico"}},
+		{"no-markup", "./testdata/no-markup", nil, []string{"/favicon.ico"}},
+		{"well-known-dir", "./testdata/well-known-dir", nil, []string{"/favicon.ico"}},
+		{"well-known-dir-probe", "./testdata/well-known-dir", []favicon.Option{favicon.ProbeWellKnownDir},
+			[]string{"/.well-known/favicon.ico", "/favicon.ico"}},
+		{"custom", "./testdata/well-known-dir", []favicon.Option{favicon.WithWellKnownPath("/icons/apple-touch-icon.png", "missing.ico")},
+			[]string{"/favicon.ico", "/icons/apple-touch-icon.png"}},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			ts := httptest.NewServer(http.FileServer(http.Dir(td.path)))
+			defer ts.Close()
+
+			opts := []favicon.Option{favicon.WithClient(ts.Client()), favicon.WithLogger(debugLogger{t})}
+			icons, err := favicon.New(append(opts, td.opts...)...).FindWellKnown(ts.URL)
+			require.Nil(t, err, "unexpected error")
+			var paths []string
+			for _, icon := range icons {
+				paths = append(paths, strings.TrimPrefix(icon.URL, ts.URL))
+			}
+			assert.ElementsMatch(t, td.x, paths, "unexpected icons")
+		})
+	}
+
+	_, err := favicon.New().FindWellKnown("/relative/path")
+	assert.NotNil(t, err, "expected error for relative URL")
+}
+
+// TestInternationalURLs verifies non-ASCII paths and hosts, spaces and
+// non-default ports give consistent icon URLs.
+func TestInternationalURLs(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.FileServer(http.Dir("./testdata/i18n")))
+	defer ts.Close()
+
+	f := favicon.New(
+		favicon.WithClient(ts.Client()),
+		favicon.WithLogger(debugLogger{t}),
+		favicon.IgnoreManifest,
+		favicon.IgnoreBrowserConfig,
+		favicon.WithWellKnownPath("图标 32x32.png"),
+	)
+	icons, err := f.Find(ts.URL + "/index.html")
+	require.Nil(t, err, "unexpected error")
+	require.Equal(t, 2, len(icons), "unexpected favicon count")
+
+	// the three references to the same file have the same URL
+	assert.Equal(t, ts.URL+"/%E5%9B%BE%E6%A0%87%2032x32.png", icons[0].URL, "unexpected URL")
+	assert.Equal(t, 32, icons[0].Width, "unexpected width")
+	assert.Equal(t, "http://cdn.xn--fsqu00a.example:8443/apple%20touch-icon.png", icons[1].URL, "unexpected URL")
+}
+
+// TestFindStream verifies streamed icons match those returned by Find.
+func TestFindStream(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name, path string
+		xcount     int
+	}{
+		{"github", "./testdata/github", 18},
+		{"kuli", "./testdata/kuli", 8},
+		{"mozilla", "./testdata/mozilla", 4},
+		{"no-markup", "./testdata/no-markup", 3},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			ts := httptest.NewServer(http.FileServer(http.Dir(td.path)))
+			defer ts.Close()
+
+			f := favicon.New(favicon.WithClient(ts.Client()), favicon.WithLogger(debugLogger{t}))
+			s, err := f.FindStream(context.Background(), ts.URL+"/index.html")
+			require.Nil(t, err, "unexpected error")
+
+			var icons []*favicon.Icon
+			for icon := range s.C {
+				icons = append(icons, icon)
+			}
+			assert.Equal(t, td.xcount, len(icons), "unexpected favicon count")
 		})
 	}
 }
@@ -107,8 +463,8 @@ func TestIgnore(t *testing.T) {
 		xcount          int
 	}{
 		// ignore well-known
-		{"github-ignore-well-known", "./testdata/github", true, false, 17},
-		{"kuli-ignore-well-known", "./testdata/kuli", true, false, 7},
+		{"github-ignore-well-known", "./testdata/github", true, false, 18},
+		{"kuli-ignore-well-known", "./testdata/kuli", true, false, 8},
 		{"mozilla-ignore-well-known", "./testdata/mozilla", true, false, 4},
 		{"no-markup-ignore-well-known", "./testdata/no-markup", true, false, 2},
 		{"manifest-only-ignore-well-known", "./testdata/manifest-only", true, false, 2},
@@ -118,8 +474,8 @@ func TestIgnore(t *testing.T) {
 		{"manifest-only-ignore-manifest", "./testdata/manifest-only", false, true, 0},
 
 		// ignore well-known & manifest
-		{"github-ignore-both", "./testdata/github", true, true, 6},
-		{"kuli-ignore-both", "./testdata/kuli", true, true, 5},
+		{"github-ignore-both", "./testdata/github", true, true, 7},
+		{"kuli-ignore-both", "./testdata/kuli", true, true, 6},
 		{"mozilla-ignore-both", "./testdata/mozilla", true, true, 4},
 		{"no-markup-ignore-both", "./testdata/no-markup", true, true, 0},
 		{"manifest-only-both", "./testdata/manifest-only", true, true, 0},
@@ -160,8 +516,8 @@ func TestFilter(t *testing.T) {
 		opts       []favicon.Option
 		xcount     int
 	}{
-		{"no-options", "./testdata/multiformat", []favicon.Option{}, 9},
-		{"only-square", "./testdata/multiformat", []favicon.Option{favicon.OnlySquare}, 6},
+		{"no-options", "./testdata/multiformat", []favicon.Option{}, 10},
+		{"only-square", "./testdata/multiformat", []favicon.Option{favicon.OnlySquare}, 7},
 		{"ignore-nosize", "./testdata/multiformat", []favicon.Option{favicon.IgnoreNoSize}, 8},
 		{"only-ico", "./testdata/multiformat", []favicon.Option{favicon.OnlyICO}, 1},
 		{"only-png", "./testdata/multiformat", []favicon.Option{favicon.OnlyPNG}, 7},
@@ -196,3 +552,641 @@ func TestFilter(t *testing.T) {
 		})
 	}
 }
+
+// TestIncludeAttrs verifies that raw attributes are attached to Icons.
+func TestIncludeAttrs(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.FileServer(http.Dir("./testdata/kuli")))
+	defer ts.Close()
+
+	find := func(opts ...favicon.Option) favicon.Icons {
+		opts = append(opts, favicon.WithClient(ts.Client()), favicon.WithLogger(debugLogger{t}), favicon.IgnoreWellKnown)
+		icons, err := favicon.New(opts...).Find(ts.URL + "/index.html")
+		require.Nil(t, err, "unexpected error")
+		return icons
+	}
+
+	for _, icon := range find() {
+		assert.Nil(t, icon.Attrs, "unexpected attributes")
+	}
+
+	var link, manifest *favicon.Icon
+	for _, icon := range find(favicon.IncludeAttrs) {
+		if strings.HasSuffix(icon.URL, "/apple-touch-icon.png") {
+			link = icon
+		}
+		if icon.Attrs != nil && icon.Attrs["src"] != "" && manifest == nil {
+			manifest = icon
+		}
+	}
+	require.NotNil(t, link, "link icon not found")
+	assert.Equal(t, "apple-touch-icon", link.Attrs["rel"], "unexpected rel")
+	assert.Equal(t, "180x180", link.Attrs["sizes"], "unexpected sizes")
+	require.NotNil(t, manifest, "manifest icon not found")
+	assert.NotEmpty(t, manifest.Attrs["sizes"], "unexpected sizes")
+}
+
+// TestWith verifies that derived Finders don't affect the original.
+func TestWith(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.FileServer(http.Dir("./testdata/multiformat")))
+	t.Cleanup(ts.Close)
+
+	f := favicon.New(favicon.WithClient(ts.Client()), favicon.WithLogger(debugLogger{t}), favicon.OnlySquare)
+	png := f.With(favicon.OnlyPNG)
+	ico := f.With(favicon.OnlyICO)
+	assert.Equal(t, []string{"OnlySquare"}, f.Filters(), "unexpected filters")
+	assert.Equal(t, []string{"OnlySquare", `OnlyMimeType("image/png")`}, png.Filters(), "unexpected filters")
+
+	tests := []struct {
+		name   string
+		f      *favicon.Finder
+		xcount int
+	}{
+		{"original", f, 7},
+		{"only-png", png, 4},
+		{"only-ico", ico, 1},
+	}
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			icons, err := td.f.Find(ts.URL + "/index.html")
+			require.Nil(t, err, "unexpected error")
+			assert.Equal(t, td.xcount, len(icons), "unexpected favicon count")
+		})
+	}
+}
+
+// TestMultiSizeFilters verifies size filters apply to each size of a <link>
+// with several sizes.
+func TestMultiSizeFilters(t *testing.T) {
+	t.Parallel()
+	html := `<link rel="icon" sizes="16x16 32x32 48x48" href="/favicon.ico">`
+	tests := []struct {
+		name string
+		opts []favicon.Option
+		x    []int // widths
+	}{
+		{"all", nil, []int{48, 32, 16}},
+		{"min", []favicon.Option{favicon.MinWidth(32)}, []int{48, 32}},
+		{"max", []favicon.Option{favicon.MaxHeight(32)}, []int{32, 16}},
+		{"range", []favicon.Option{favicon.MinWidth(20), favicon.MaxWidth(40)}, []int{32}},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			icons, err := htmlFinder(t, td.opts...).FindReader(strings.NewReader(html), favicon.BaseURL("https://example.com/"))
+			require.Nil(t, err, "unexpected error")
+			var widths []int
+			for _, icon := range icons {
+				assert.Equal(t, "https://example.com/favicon.ico", icon.URL, "unexpected URL")
+				widths = append(widths, icon.Width)
+			}
+			assert.Equal(t, td.x, widths, "unexpected widths")
+		})
+	}
+}
+
+// TestIconColor verifies the colours of Safari pinned tab and Windows tile icons.
+func TestIconColor(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name, path string
+		url, color string
+	}{
+		{"mask-icon", "testdata/kuli/index.html", "https://example.com/img/icons/safari-pinned-tab.svg", "#444444"},
+		{"tile", "testdata/mstile/index.html", "https://example.com/mstile-144x144.png", "#2b5797"},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			file, err := os.Open(td.path)
+			require.Nil(t, err, "unexpected error")
+			defer file.Close()
+
+			icons, err := htmlFinder(t).FindReader(file, favicon.BaseURL("https://example.com/"))
+			require.Nil(t, err, "unexpected error")
+
+			var icon *favicon.Icon
+			for _, i := range icons {
+				if i.URL == td.url {
+					icon = i
+				}
+			}
+			require.NotNil(t, icon, "icon not found")
+			assert.Equal(t, td.color, icon.Color, "unexpected colour")
+		})
+	}
+}
+
+// TestConcurrentUse shares one Finder between goroutines. Run with -race.
+func TestConcurrentUse(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.FileServer(http.Dir("./testdata/kuli")))
+	defer ts.Close()
+
+	f := favicon.New(
+		favicon.WithClient(ts.Client()),
+		favicon.WithLogger(debugLogger{t}),
+		favicon.WithSource(staticSource{urls: []string{"/static/custom-64x64.png"}}),
+	)
+	url := ts.URL + "/index.html"
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			icons, err := f.Find(url)
+			assert.Nil(t, err, "unexpected error")
+			assert.Equal(t, 9, len(icons), "unexpected favicon count")
+		}()
+		go func() {
+			defer wg.Done()
+			r, err := f.With(favicon.OnlyPNG).FindDetailed(url)
+			assert.Nil(t, err, "unexpected error")
+			assert.Equal(t, "Kulturliste Düsseldorf", r.ManifestName, "unexpected manifest name")
+		}()
+		go func() {
+			defer wg.Done()
+			s, err := f.FindStream(context.Background(), url)
+			if !assert.Nil(t, err, "unexpected error") {
+				return
+			}
+			var n int
+			for range s.C {
+				n++
+			}
+			assert.Equal(t, 9, n, "unexpected favicon count")
+		}()
+	}
+	wg.Wait()
+}
+
+// TestMedia verifies light and dark mode variants are kept.
+func TestMedia(t *testing.T) {
+	t.Parallel()
+	html := `<html><head>
+		<link rel="icon" href="/light.svg" media="(prefers-color-scheme: light)">
+		<link rel="icon" href="/dark.svg" media="(prefers-color-scheme: dark)">
+		<link rel="icon" href="/icon.png" sizes="32x32" media="(prefers-color-scheme: light)">
+		<link rel="icon" href="/icon.png" sizes="32x32" media="(prefers-color-scheme: dark)">
+		<link rel="icon" href="/favicon.ico">
+	</head></html>`
+	icons, err := htmlFinder(t).FindReader(strings.NewReader(html), favicon.BaseURL("https://example.com/"))
+	require.Nil(t, err, "unexpected error")
+
+	var found []string
+	for _, icon := range icons {
+		found = append(found, icon.URL+" "+icon.Media)
+	}
+	assert.ElementsMatch(t, []string{
+		"https://example.com/light.svg (prefers-color-scheme: light)",
+		"https://example.com/dark.svg (prefers-color-scheme: dark)",
+		"https://example.com/icon.png (prefers-color-scheme: light)",
+		"https://example.com/icon.png (prefers-color-scheme: dark)",
+		"https://example.com/favicon.ico ",
+	}, found, "unexpected icons")
+}
+
+// TestStartupImages verifies iOS launch screen images are found if requested.
+func TestStartupImages(t *testing.T) {
+	t.Parallel()
+	html := `<html><head>
+		<link rel="apple-touch-startup-image" href="/launch-1125x2436.png"
+			media="(device-width: 375px) and (device-height: 812px) and (-webkit-device-pixel-ratio: 3) and (orientation: portrait)">
+		<link rel="apple-touch-startup-image" href="/launch-landscape.png"
+			media="(device-width: 768px) and (device-height: 1024px) and (-webkit-device-pixel-ratio: 2) and (orientation: landscape)">
+		<link rel="apple-touch-startup-image" href="/launch.png">
+		<link rel="icon" type="image/png" sizes="16x16" href="/favicon-16x16.png">
+	</head></html>`
+
+	icons, err := htmlFinder(t).FindReader(strings.NewReader(html), favicon.BaseURL("https://example.com/"))
+	require.Nil(t, err, "unexpected error")
+	require.Equal(t, 1, len(icons), "unexpected favicon count")
+
+	icons, err = htmlFinder(t, favicon.IncludeStartupImages).FindReader(strings.NewReader(html),
+		favicon.BaseURL("https://example.com/"))
+	require.Nil(t, err, "unexpected error")
+	require.Equal(t, 4, len(icons), "unexpected favicon count")
+
+	tests := []struct {
+		url           string
+		width, height int
+		scale         float64
+	}{
+		{"https://example.com/favicon-16x16.png", 16, 16, 0},
+		{"https://example.com/launch-landscape.png", 2048, 1536, 2},
+		{"https://example.com/launch-1125x2436.png", 1125, 2436, 3},
+		{"https://example.com/launch.png", 0, 0, 0},
+	}
+	for i, td := range tests {
+		assert.Equal(t, td.url, icons[i].URL, "unexpected URL")
+		assert.Equal(t, td.width, icons[i].Width, "unexpected width")
+		assert.Equal(t, td.height, icons[i].Height, "unexpected height")
+		assert.Equal(t, td.scale, icons[i].Scale, "unexpected scale")
+	}
+
+	// bogus media queries give no size or a limited one
+	html = `<link rel="apple-touch-startup-image" href="/launch-huge.png"
+			media="(device-width: 99999999999999999999px) and (device-height: 812px)">
+		<link rel="apple-touch-startup-image" href="/launch-dense.png"
+			media="(device-width: 375px) and (device-height: 812px) and (-webkit-device-pixel-ratio: 1000000)">`
+	icons, err = htmlFinder(t, favicon.IncludeStartupImages).FindReader(strings.NewReader(html),
+		favicon.BaseURL("https://example.com/"))
+	require.Nil(t, err, "unexpected error")
+	require.Equal(t, 2, len(icons), "unexpected favicon count")
+	assert.Equal(t, "https://example.com/launch-dense.png", icons[0].URL, "unexpected URL")
+	assert.Equal(t, 1500, icons[0].Width, "unexpected width")
+	assert.Equal(t, 4.0, icons[0].Scale, "unexpected scale")
+	assert.Equal(t, "https://example.com/launch-huge.png", icons[1].URL, "unexpected URL")
+	assert.Equal(t, 0, icons[1].Width, "unexpected width")
+}
+
+// TestNoscript verifies icons in <noscript> elements are found if requested.
+func TestNoscript(t *testing.T) {
+	t.Parallel()
+	html := `<html><head>
+		<noscript>
+			<link rel="icon" type="image/png" sizes="32x32" href="/favicon-32x32.png">
+			<link rel="apple-touch-icon" sizes="180x180" href="/apple-touch-icon.png">
+		</noscript>
+		<link rel="icon" type="image/png" sizes="16x16" href="/favicon-16x16.png">
+	</head><body><noscript><link rel="icon" href="/favicon.svg"></noscript></body></html>`
+
+	icons, err := htmlFinder(t).FindReader(strings.NewReader(html), favicon.BaseURL("https://example.com/"))
+	require.Nil(t, err, "unexpected error")
+	require.Equal(t, 1, len(icons), "unexpected favicon count")
+
+	icons, err = htmlFinder(t, favicon.IncludeNoscript).FindReader(strings.NewReader(html),
+		favicon.BaseURL("https://example.com/"))
+	require.Nil(t, err, "unexpected error")
+	require.Equal(t, 4, len(icons), "unexpected favicon count")
+
+	x := []string{
+		"https://example.com/apple-touch-icon.png",
+		"https://example.com/favicon-32x32.png",
+		"https://example.com/favicon-16x16.png",
+		"https://example.com/favicon.svg",
+	}
+	for i, url := range x {
+		assert.Equal(t, url, icons[i].URL, "unexpected URL")
+	}
+}
+
+// TestMetaRefresh verifies redirect stubs are followed.
+func TestMetaRefresh(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.FileServer(http.Dir("./testdata/refresh")))
+	defer ts.Close()
+
+	f := favicon.New(
+		favicon.WithClient(ts.Client()),
+		favicon.WithLogger(debugLogger{t}),
+		favicon.IgnoreManifest,
+		favicon.IgnoreWellKnown,
+		favicon.IgnoreBrowserConfig,
+	)
+	res, err := f.FindDetailed(ts.URL + "/index.html")
+	require.Nil(t, err, "unexpected error")
+	assert.Equal(t, "Site", res.Title, "unexpected title")
+	require.Equal(t, 1, len(res.Icons), "unexpected favicon count")
+	assert.Equal(t, ts.URL+"/site/icon-32x32.png", res.Icons[0].URL, "unexpected URL")
+
+	// pages that refresh after a long delay aren't stubs
+	res, err = f.FindDetailed(ts.URL + "/slow.html")
+	require.Nil(t, err, "unexpected error")
+	assert.Equal(t, "Slow", res.Title, "unexpected title")
+	require.Equal(t, 1, len(res.Icons), "unexpected favicon count")
+	assert.Equal(t, ts.URL+"/og.png", res.Icons[0].URL, "unexpected URL")
+
+	// loops stop at the page that leads back
+	res, err = f.FindDetailed(ts.URL + "/loop/a.html")
+	require.Nil(t, err, "unexpected error")
+	assert.Equal(t, 0, len(res.Icons), "unexpected favicon count")
+
+	// a page that refreshes itself is used
+	res, err = f.FindDetailed(ts.URL + "/self.html")
+	require.Nil(t, err, "unexpected error")
+	assert.Equal(t, "Self", res.Title, "unexpected title")
+	require.Equal(t, 1, len(res.Icons), "unexpected favicon count")
+	assert.Equal(t, ts.URL+"/og.png", res.Icons[0].URL, "unexpected URL")
+}
+
+// TestHTTPRedirectLoop verifies HTTP redirect loops are reported.
+func TestHTTPRedirectLoop(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a":
+			http.Redirect(w, r, "/b", http.StatusFound)
+		case "/b":
+			http.Redirect(w, r, "/c", http.StatusMovedPermanently)
+		default:
+			http.Redirect(w, r, "/b", http.StatusFound)
+		}
+	}))
+	defer ts.Close()
+
+	_, err := favicon.New(favicon.WithClient(ts.Client()), favicon.WithLogger(debugLogger{t})).Find(ts.URL + "/a")
+	var e *favicon.RedirectLoopError
+	require.True(t, errors.As(err, &e), "expected RedirectLoopError")
+	assert.Equal(t, []string{ts.URL + "/b", ts.URL + "/c", ts.URL + "/b"}, e.URLs, "unexpected loop")
+}
+
+// TestFrames verifies wrapper pages around a single frame are followed.
+func TestFrames(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.FileServer(http.Dir("./testdata/frames")))
+	t.Cleanup(ts.Close)
+
+	tests := []struct {
+		name, page string
+		follow     bool
+		x          string // title
+	}{
+		{"frameset", "index.html", false, "Intranet"},
+		{"frameset-follow", "index.html", true, "Site"},
+		{"iframe", "iframe.html", false, "Wrapper"},
+		{"iframe-follow", "iframe.html", true, "Site"},
+		{"not-wrapper", "content.html", true, "Video"},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			opts := []favicon.Option{
+				favicon.WithClient(ts.Client()),
+				favicon.WithLogger(debugLogger{t}),
+				favicon.IgnoreManifest,
+				favicon.IgnoreWellKnown,
+				favicon.IgnoreBrowserConfig,
+			}
+			if td.follow {
+				opts = append(opts, favicon.FollowFrames)
+			}
+			res, err := favicon.New(opts...).FindDetailed(ts.URL + "/" + td.page)
+			require.Nil(t, err, "unexpected error")
+			assert.Equal(t, td.x, res.Title, "unexpected title")
+			if td.x == "Site" {
+				require.Equal(t, 1, len(res.Icons), "unexpected favicon count")
+				assert.Equal(t, ts.URL+"/site/icon-32x32.png", res.Icons[0].URL, "unexpected URL")
+			} else {
+				assert.Equal(t, 0, len(res.Icons), "unexpected favicon count")
+			}
+		})
+	}
+}
+
+// TestDirectImage verifies image URLs are returned as Icons.
+func TestDirectImage(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.FileServer(http.Dir("./testdata/well-known-dir")))
+	t.Cleanup(ts.Close)
+
+	tests := []struct {
+		name string
+		opts []favicon.Option
+		x    []string // paths
+	}{
+		{"well-known", nil, []string{"/icons/apple-touch-icon.png", "/favicon.ico"}},
+		{"ignore-well-known", []favicon.Option{favicon.IgnoreWellKnown}, []string{"/icons/apple-touch-icon.png"}},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			opts := []favicon.Option{favicon.WithClient(ts.Client()), favicon.WithLogger(debugLogger{t})}
+			icons, err := favicon.New(append(opts, td.opts...)...).Find(ts.URL + "/icons/apple-touch-icon.png")
+			require.Nil(t, err, "unexpected error")
+			var paths []string
+			for _, icon := range icons {
+				paths = append(paths, strings.TrimPrefix(icon.URL, ts.URL))
+			}
+			require.Equal(t, td.x, paths, "unexpected icons")
+
+			icon := icons[0]
+			assert.Equal(t, "image/png", icon.MimeType, "unexpected MIME type")
+			assert.Equal(t, 16, icon.Width, "unexpected width")
+			assert.Equal(t, 16, icon.Height, "unexpected height")
+			assert.Equal(t, "direct", icon.Source, "unexpected source")
+		})
+	}
+}
+
+// TestFollowAMP verifies icons are read from AMP and canonical pages.
+func TestFollowAMP(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.FileServer(http.Dir("./testdata/amp")))
+	t.Cleanup(ts.Close)
+
+	tests := []struct {
+		name, page string
+		follow     bool
+		x          []string // paths
+	}{
+		{"canonical", "/index.html", false, []string{"/icon-32x32.png"}},
+		{"canonical-follow", "/index.html", true, []string{"/apple-touch-icon.png", "/icon-32x32.png"}},
+		{"amp", "/amp.html", false, []string{"/apple-touch-icon.png"}},
+		{"amp-follow", "/amp.html", true, []string{"/apple-touch-icon.png", "/icon-32x32.png"}},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			opts := []favicon.Option{
+				favicon.WithClient(ts.Client()),
+				favicon.WithLogger(debugLogger{t}),
+				favicon.IgnoreManifest,
+				favicon.IgnoreWellKnown,
+				favicon.IgnoreBrowserConfig,
+			}
+			if td.follow {
+				opts = append(opts, favicon.FollowAMP)
+			}
+			res, err := favicon.New(opts...).FindDetailed(ts.URL + td.page)
+			require.Nil(t, err, "unexpected error")
+			require.Nil(t, res.Err(), "unexpected source error")
+			var paths []string
+			for _, icon := range res.Icons {
+				paths = append(paths, strings.TrimPrefix(icon.URL, ts.URL))
+			}
+			assert.Equal(t, td.x, paths, "unexpected icons")
+		})
+	}
+}
+
+// TestDescription verifies Icons are described by alt text etc.
+func TestDescription(t *testing.T) {
+	t.Parallel()
+	html := `<html><head>
+		<link rel="icon" href="/favicon.png" title="Example">
+		<meta property="og:image" content="/og.png">
+		<meta property="og:image:alt" content="Open Graph image">
+		<meta name="twitter:image" content="/twitter.png">
+		<meta name="twitter:image:alt" content="Twitter image">
+	</head><body>
+		<img itemprop="logo" src="/logo.png" alt="Logo">
+		<img itemprop="image" src="/image.jpg" title="Image">
+	</body></html>`
+
+	icons, err := htmlFinder(t).FindReader(strings.NewReader(html), favicon.BaseURL("https://example.com/"))
+	require.Nil(t, err, "unexpected error")
+
+	found := map[string]string{}
+	for _, icon := range icons {
+		found[strings.TrimPrefix(icon.URL, "https://example.com")] = icon.Description
+	}
+	assert.Equal(t, map[string]string{
+		"/favicon.png": "Example",
+		"/og.png":      "Open Graph image",
+		"/twitter.png": "Twitter image",
+		"/logo.png":    "Logo",
+		"/image.jpg":   "Image",
+	}, found, "unexpected descriptions")
+}
+
+// logger that records messages.
+type recordLogger struct {
+	mu   sync.Mutex
+	msgs []string
+}
+
+func (l *recordLogger) Printf(format string, v ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.msgs = append(l.msgs, fmt.Sprintf(format, v...))
+}
+
+// TestFilterLogging verifies rejected Icons are logged with the filter's name.
+func TestFilterLogging(t *testing.T) {
+	t.Parallel()
+	html := `<link rel="icon" href="/favicon.ico" sizes="32x32">
+		<link rel="icon" href="/icon-16.png" sizes="16x16">
+		<link rel="icon" href="/icon-64.png" sizes="64x64">
+		<link rel="icon" href="/icon-128.png" sizes="128x128">
+		<link rel="icon" href="/icon-256.png" sizes="256x256">`
+
+	log := &recordLogger{}
+	f := favicon.New(
+		favicon.WithLogger(log),
+		favicon.IgnoreManifest,
+		favicon.IgnoreWellKnown,
+		favicon.IgnoreBrowserConfig,
+		favicon.OnlyPNG,
+		favicon.MinWidth(32),
+		favicon.WithFilter(func(icon *favicon.Icon) *favicon.Icon {
+			if icon.Width > 200 {
+				return nil
+			}
+			return icon
+		}),
+		favicon.NamedFilter("no-128", func(icon *favicon.Icon) *favicon.Icon {
+			if icon.Width == 128 {
+				return nil
+			}
+			return icon
+		}),
+	)
+	icons, err := f.FindReader(strings.NewReader(html), favicon.BaseURL("https://example.com/"))
+	require.Nil(t, err, "unexpected error")
+	require.Equal(t, 1, len(icons), "unexpected favicon count")
+	assert.Equal(t, "https://example.com/icon-64.png", icons[0].URL, "unexpected URL")
+
+	assert.Equal(t, []string{`OnlyMimeType("image/png")`, "MinWidth(32)", "#3", "no-128"}, f.Filters(), "unexpected filters")
+
+	var rejected []string
+	for _, s := range log.msgs {
+		if strings.Contains(s, "rejected by") {
+			rejected = append(rejected, s)
+		}
+	}
+	assert.ElementsMatch(t, []string{
+		`ignored https://example.com/favicon.ico: rejected by filter OnlyMimeType("image/png")`,
+		`ignored https://example.com/icon-16.png: rejected by filter MinWidth(32)`,
+		`ignored https://example.com/icon-256.png: rejected by filter #3`,
+		`ignored https://example.com/icon-128.png: rejected by filter no-128`,
+	}, rejected, "unexpected log messages")
+}
+
+// TestKind verifies Icons are classified and can be filtered by Kind and Source.
+func TestKind(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name, path string
+		opts       []favicon.Option
+		x          []string // kind/source
+	}{
+		{"kuli", "./testdata/kuli", nil, []string{
+			"app/manifest", "app/manifest", "image/opengraph", "site/link", "site/link",
+			"site/link", "site/link", "touch/link",
+		}},
+		{"no-markup", "./testdata/no-markup", nil, []string{
+			"app/manifest", "app/manifest", "site/well-known",
+		}},
+		{"only-site", "./testdata/kuli", []favicon.Option{favicon.OnlySiteIcons}, []string{
+			"site/link", "site/link", "site/link", "site/link",
+		}},
+		{"only-touch", "./testdata/kuli", []favicon.Option{favicon.OnlyTouchIcons}, []string{"touch/link"}},
+		{"only-app-or-image", "./testdata/kuli", []favicon.Option{favicon.OnlyKind(favicon.KindApp, favicon.KindImage)},
+			[]string{"app/manifest", "app/manifest", "image/opengraph"}},
+		{"exclude-well-known", "./testdata/no-markup", []favicon.Option{favicon.ExcludeWellKnownProbes},
+			[]string{"app/manifest", "app/manifest"}},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			ts := httptest.NewServer(http.FileServer(http.Dir(td.path)))
+			defer ts.Close()
+
+			opts := []favicon.Option{favicon.WithClient(ts.Client()), favicon.WithLogger(debugLogger{t})}
+			icons, err := favicon.New(append(opts, td.opts...)...).Find(ts.URL + "/index.html")
+			require.Nil(t, err, "unexpected error")
+
+			var found []string
+			for _, icon := range icons {
+				found = append(found, string(icon.Kind)+"/"+icon.Source)
+			}
+			assert.ElementsMatch(t, td.x, found, "unexpected kinds")
+		})
+	}
+}
+
+// TestFetchPriority verifies fetchpriority and importance attributes are read
+// and break ties between icons of the same size.
+func TestFetchPriority(t *testing.T) {
+	t.Parallel()
+	html := `<html><head>
+		<link rel="icon" href="/a.png" sizes="32x32" fetchpriority="low">
+		<link rel="icon" href="/b.ico" sizes="32x32" importance="HIGH">
+		<link rel="icon" href="/c.png" sizes="32x32">
+		<link rel="icon" href="/d.png" sizes="32x32" fetchpriority="auto" importance="high">
+		<link rel="icon" href="/e.png" sizes="64x64" fetchpriority="low">
+	</head></html>`
+
+	icons, err := htmlFinder(t).FindReader(strings.NewReader(html), favicon.BaseURL("https://example.com/"))
+	require.Nil(t, err, "unexpected error")
+
+	var found [][2]string
+	for _, icon := range icons {
+		found = append(found, [2]string{strings.TrimPrefix(icon.URL, "https://example.com"), icon.Priority})
+	}
+	x := [][2]string{
+		{"/e.png", "low"}, // larger icons still sort first
+		{"/b.ico", "high"},
+		{"/c.png", ""},
+		{"/d.png", ""},
+		{"/a.png", "low"},
+	}
+	assert.Equal(t, x, found, "unexpected icons")
+}
@@ -0,0 +1,167 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+// Created on 2026-10-16
+
+// Package favicontest provides utilities for regression-testing code that
+// uses go-favicon.
+//
+// Golden files store icon sets in a canonical, line-oriented text format
+// that's easy to review and diff. Set the environment variable
+// FAVICON_UPDATE_GOLDEN to a non-empty value to (re-)write golden files
+// instead of comparing against them.
+package favicontest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/muzhou233/go-favicon"
+)
+
+// UpdateEnv is the environment variable that causes Check to update golden
+// files.
+const UpdateEnv = "FAVICON_UPDATE_GOLDEN"
+
+// Marshal returns icons in golden format: one line per icon, in the given
+// order. Lines contain the icon's URL, MIME type, size, Kind and Source,
+// followed by any non-empty Scale, Color, Media, Platform, Purpose,
+// Shortcut and Priority.
+//
+// If baseURL is not empty, it is removed from the start of icon URLs, so
+// results from servers on random ports, e.g. httptest, are comparable.
+// Hashes aren't included, as they depend on the full URL.
+func Marshal(icons favicon.Icons, baseURL string) []byte {
+	var buf bytes.Buffer
+	for _, i := range icons {
+		url := i.URL
+		if baseURL != "" {
+			url = strings.TrimPrefix(url, strings.TrimSuffix(baseURL, "/"))
+		}
+		fields := []string{
+			quote(url),
+			quote(i.MimeType),
+			fmt.Sprintf("%dx%d", i.Width, i.Height),
+			quote(string(i.Kind)),
+			quote(i.Source),
+		}
+		if i.Scale != 0 {
+			fields = append(fields, "scale="+strconv.FormatFloat(i.Scale, 'g', -1, 64))
+		}
+		for _, kv := range [][2]string{
+			{"color", i.Color},
+			{"media", i.Media},
+			{"platform", i.Platform},
+			{"purpose", strings.Join(i.Purpose, ",")},
+			{"shortcut", i.Shortcut},
+			{"priority", i.Priority},
+		} {
+			if kv[1] != "" {
+				fields = append(fields, kv[0]+"="+quote(kv[1]))
+			}
+		}
+		buf.WriteString(strings.Join(fields, " "))
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// quote s if it's empty or contains whitespace or quotes.
+func quote(s string) string {
+	if s == "" || strings.ContainsAny(s, " \t\r\n\"") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// Diff compares two golden files line by line. It returns an empty string
+// if they're the same, otherwise the differing lines prefixed with "-"
+// (only in want) or "+" (only in got) and the common lines with " ".
+func Diff(want, got []byte) string {
+	if bytes.Equal(want, got) {
+		return ""
+	}
+	var (
+		a = splitLines(want)
+		b = splitLines(got)
+		// lcs[i][j] is the length of the longest common subsequence
+		// of a[i:] and b[j:]
+		lcs = make([][]int, len(a)+1)
+	)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var (
+		sb   strings.Builder
+		i, j int
+	)
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			sb.WriteString("  " + a[i] + "\n")
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			sb.WriteString("- " + a[i] + "\n")
+			i++
+		default:
+			sb.WriteString("+ " + b[j] + "\n")
+			j++
+		}
+	}
+	return sb.String()
+}
+
+func splitLines(data []byte) []string {
+	s := strings.TrimSuffix(string(data), "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+// Check compares icons to the golden file at path and reports any
+// differences as a test error. If the environment variable UpdateEnv is
+// set, the golden file is written instead. See Marshal for baseURL.
+func Check(t testing.TB, path string, icons favicon.Icons, baseURL string) {
+	t.Helper()
+	got := Marshal(icons, baseURL)
+
+	if os.Getenv(UpdateEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("create golden directory: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0o600); err != nil {
+			t.Fatalf("write golden file: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("golden file %q does not exist; set %s=1 to create it", path, UpdateEnv)
+		}
+		t.Fatalf("read golden file: %v", err)
+	}
+	if diff := Diff(want, got); diff != "" {
+		t.Errorf("icons differ from golden file %q (-want +got):\n%s", path, diff)
+	}
+}
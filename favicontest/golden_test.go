@@ -0,0 +1,60 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+// Created on 2026-10-16
+
+package favicontest_test
+
+import (
+	"testing"
+
+	"github.com/muzhou233/go-favicon"
+	"github.com/muzhou233/go-favicon/favicontest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMarshal verifies the golden format.
+func TestMarshal(t *testing.T) {
+	t.Parallel()
+	icons := favicon.Icons{
+		{
+			URL: "http://127.0.0.1:8080/icon.png", MimeType: "image/png", Width: 512, Height: 512,
+			Kind: favicon.KindApp, Source: "manifest", Scale: 2, Purpose: []string{"maskable", "any"},
+		},
+		{
+			URL: "http://127.0.0.1:8080/favicon.ico", MimeType: "image/x-icon",
+			Kind: favicon.KindSite, Source: "link", Media: "(prefers-color-scheme: dark)",
+		},
+		{URL: "https://example.com/x.svg", MimeType: "image/svg+xml"},
+	}
+	x := `/icon.png image/png 512x512 app manifest scale=2 purpose=maskable,any
+/favicon.ico image/x-icon 0x0 site link media="(prefers-color-scheme: dark)"
+https://example.com/x.svg image/svg+xml 0x0 "" ""
+`
+	assert.Equal(t, x, string(favicontest.Marshal(icons, "http://127.0.0.1:8080/")), "unexpected golden data")
+	assert.Equal(t, "", string(favicontest.Marshal(nil, "")), "unexpected golden data")
+}
+
+// TestDiff verifies differences between golden files.
+func TestDiff(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name, want, got, x string
+	}{
+		{"equal", "a\nb\n", "a\nb\n", ""},
+		{"empty", "", "", ""},
+		{"added", "a\nc\n", "a\nb\nc\n", "  a\n+ b\n  c\n"},
+		{"removed", "a\nb\nc\n", "a\nc\n", "  a\n- b\n  c\n"},
+		{"changed", "a\nb\n", "a\nB\n", "  a\n- b\n+ B\n"},
+		{"reordered", "a\nb\n", "b\na\n", "- a\n  b\n+ a\n"},
+		{"from empty", "", "a\n", "+ a\n"},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, td.x, favicontest.Diff([]byte(td.want), []byte(td.got)), "unexpected diff")
+		})
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+// Created on 2026-10-16
+
+package favicon
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	gq "github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// parse HTML from r. With HeadOnly, only the document's <head> is read.
+// Errors reading r, e.g. a timeout, are returned as they are; only
+// failures to parse the markup are ErrInvalidHTML.
+func (p *parser) newDocument(r io.Reader) (*gq.Document, error) {
+	er := &errReader{r: r}
+	r = er
+	if p.find.headOnly {
+		b, err := readHead(r)
+		if err != nil {
+			return nil, htmlError(er, err)
+		}
+		p.find.log.Printf("read %d bytes of <head>", len(b))
+		r = bytes.NewReader(b)
+	}
+	doc, err := gq.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, htmlError(er, err)
+	}
+	return doc, nil
+}
+
+// reader that records the first error other than io.EOF returned by r, so
+// that read failures can be told apart from invalid markup.
+type errReader struct {
+	r   io.Reader
+	err error
+}
+
+func (r *errReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	if err != nil && !errors.Is(err, io.EOF) && r.err == nil {
+		r.err = err
+	}
+	return n, err
+}
+
+// return the error reading r if there was one, otherwise err as an
+// ErrInvalidHTML.
+func htmlError(r *errReader, err error) error {
+	if r.err != nil {
+		return fmt.Errorf("read HTML: %w", r.err)
+	}
+	return fmt.Errorf("%w: %w", ErrInvalidHTML, err)
+}
+
+// read HTML from r up to the end of the <head>, i.e. a </head> or <body> tag
+// or any other element that can't be in the <head>.
+func readHead(r io.Reader) ([]byte, error) {
+	var (
+		buf bytes.Buffer
+		z   = html.NewTokenizer(r)
+	)
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if err := z.Err(); !errors.Is(err, io.EOF) {
+				return nil, err
+			}
+			return buf.Bytes(), nil
+		case html.EndTagToken:
+			if name, _ := z.TagName(); string(name) == "head" {
+				return buf.Bytes(), nil
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if name, _ := z.TagName(); !isHeadTag(string(name)) {
+				return buf.Bytes(), nil
+			}
+		}
+		buf.Write(z.Raw())
+	}
+}
+
+// returns true if an element may appear in (or before) the <head>.
+func isHeadTag(name string) bool {
+	switch name {
+	case "html", "head", "base", "link", "meta", "noscript", "script", "style", "template", "title":
+		return true
+	}
+	return false
+}
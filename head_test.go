@@ -0,0 +1,66 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+// Created on 2026-10-16
+
+package favicon_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/muzhou233/go-favicon"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// reader that fails if read.
+type failReader struct{}
+
+func (failReader) Read([]byte) (int, error) { return 0, errors.New("read past <head>") }
+
+// TestHeadOnly verifies only the <head> is read with HeadOnly.
+func TestHeadOnly(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name, head string
+		x          []string // icon paths
+	}{
+		{"end-head", `<!DOCTYPE html><html><head>
+			<link rel="icon" href="/head.png">
+			<script>document.write("<link rel='icon' href='/script.png'>")</script>
+			</head>`, []string{"/head.png"}},
+		{"body", `<html><head><title>Test</title><link rel="icon" href="/head.png"><body>`, []string{"/head.png"}},
+		{"implicit", `<link rel="icon" href="/head.png"><div>`, []string{"/head.png"}},
+		{"noscript", `<meta charset="utf-8"><noscript><link rel="icon" href="/head.png"></noscript><p>`, []string{"/head.png"}},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			r := io.MultiReader(strings.NewReader(td.head), failReader{})
+			icons, err := htmlFinder(t, favicon.HeadOnly, favicon.IncludeNoscript).FindReader(r,
+				favicon.BaseURL("https://example.com/"))
+			require.Nil(t, err, "unexpected error")
+			var paths []string
+			for _, icon := range icons {
+				paths = append(paths, strings.TrimPrefix(icon.URL, "https://example.com"))
+			}
+			assert.Equal(t, td.x, paths, "unexpected icons")
+		})
+	}
+
+	// icons in body are ignored
+	html := `<html><head><link rel="icon" href="/head.png"></head>
+		<body><img itemprop="logo" src="/logo.png"></body></html>`
+	icons, err := htmlFinder(t).FindReader(strings.NewReader(html), favicon.BaseURL("https://example.com/"))
+	require.Nil(t, err, "unexpected error")
+	assert.Equal(t, 2, len(icons), "unexpected favicon count")
+
+	icons, err = htmlFinder(t, favicon.HeadOnly).FindReader(strings.NewReader(html), favicon.BaseURL("https://example.com/"))
+	require.Nil(t, err, "unexpected error")
+	assert.Equal(t, 1, len(icons), "unexpected favicon count")
+}
@@ -5,79 +5,416 @@
 package favicon
 
 import (
+	"bufio"
+	"fmt"
 	"io"
+	"math"
 	urls "net/url"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 
 	gq "github.com/PuerkitoBio/goquery"
-	"github.com/pingcap/errors"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/transform"
 )
 
 // entry point for URLs.
-func (p *parser) parseURL(url string) ([]*Icon, error) {
+func (p *parser) parseURL(u *urls.URL) (*Result, error) {
+	if len(p.find.pinned) > 0 {
+		p.baseURL = u
+		return p.finish(p.pinnedIcons())
+	}
+	doc, err := p.fetchDocument(u)
+	if err != nil {
+		return nil, err
+	}
+	if p.image != nil {
+		var icons []*Icon
+		for _, fn := range p.imageSources() {
+			icons = append(icons, fn()...)
+		}
+		return p.finish(icons)
+	}
+	return p.parse(doc)
+}
+
+// parse a URL string, returning an error that describes the problem.
+func parseURLString(url string) (*urls.URL, error) {
 	u, err := urls.Parse(url)
 	if err != nil {
-		return nil, errors.Wrap(err, "invalid URL")
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	return u, nil
+}
+
+// maximum number of <meta http-equiv="refresh"> redirects and frames followed.
+const maxRedirects = 3
+
+// retrieve and parse the HTML page at URL. If the page is a stub that
+// redirects with <meta http-equiv="refresh">, or (with FollowFrames) a
+// wrapper around a single frame, the target page is retrieved instead.
+// The final page's URL becomes the base URL. If a target was already
+// retrieved, e.g. a page that refreshes itself, the current page is used.
+func (p *parser) fetchDocument(u *urls.URL) (*gq.Document, error) {
+	var visited []string
+	for i := 0; ; i++ {
+		visited = append(visited, u.String())
+		doc, err := p.fetchPage(u)
+		if err != nil {
+			return nil, err
+		}
+		if i == maxRedirects || declaresIcons(doc) {
+			return doc, nil
+		}
+
+		what := "meta refresh"
+		target := refreshTarget(doc)
+		if target == "" && p.find.followFrames {
+			what, target = "frame", frameTarget(doc)
+		}
+		if target == "" {
+			return doc, nil
+		}
+		next, err := u.Parse(target)
+		if err != nil {
+			p.find.log.Printf("[WARNING] invalid %s URL %q: %v", what, target, err)
+			return doc, nil
+		}
+		if checkLoop(next.String(), visited) != nil {
+			p.find.log.Printf("not following %s to %s: already retrieved", what, next)
+			return doc, nil
+		}
+		p.find.log.Printf("following %s to %s ...", what, next)
+		u = next
+	}
+}
+
+// returns true if doc has any <link> tags with "icon" in their rel.
+func declaresIcons(doc *gq.Document) bool {
+	found := false
+	doc.Find("link[rel]").EachWithBreak(func(i int, sel *gq.Selection) bool {
+		rel, _ := sel.Attr("rel")
+		found = strings.Contains(strings.ToLower(rel), "icon")
+		return !found
+	})
+	return found
+}
+
+var rxRefresh = regexp.MustCompile(`(?i)^\s*(\d*(?:\.\d*)?)\s*[;,]\s*(?:url\s*=\s*)?(.+)$`)
+
+// longest <meta http-equiv="refresh"> delay, in seconds, of a redirect stub.
+// Pages that refresh after longer are real pages that reload periodically.
+const maxRefreshDelay = 1
+
+// return the target URL of a page that redirects with
+// <meta http-equiv="refresh">, or an empty string.
+func refreshTarget(doc *gq.Document) string {
+	var target string
+	doc.Find("meta[http-equiv]").EachWithBreak(func(i int, sel *gq.Selection) bool {
+		if equiv, _ := sel.Attr("http-equiv"); !strings.EqualFold(equiv, "refresh") {
+			return true
+		}
+		content, _ := sel.Attr("content")
+		m := rxRefresh.FindStringSubmatch(content)
+		if m == nil {
+			return false
+		}
+		if delay, err := strconv.ParseFloat(m[1], 64); err == nil && delay > maxRefreshDelay {
+			return false
+		}
+		target = strings.Trim(strings.TrimSpace(m[2]), `'"`)
+		return false
+	})
+	return target
+}
+
+// return the source URL of the frame of a page that is only a wrapper
+// around a single <frame> or <iframe>, or an empty string.
+func frameTarget(doc *gq.Document) string {
+	if frames := doc.Find("frameset frame[src]"); frames.Length() == 1 {
+		return strings.TrimSpace(frames.AttrOr("src", ""))
+	}
+	// ignore invisible elements; anything else means the page has content
+	body := doc.Find("body").Children().Not("script, noscript, style")
+	if body.Length() == 1 && gq.NodeName(body) == "iframe" {
+		return strings.TrimSpace(body.AttrOr("src", ""))
 	}
+	return ""
+}
+
+// retrieve and parse the HTML page at URL, which also becomes the base URL.
+func (p *parser) fetchPage(u *urls.URL) (*gq.Document, error) {
 	p.baseURL = u
 
-	rc, err := p.find.fetchURL(url)
+	resp, err := p.find.fetch(p.ctx, u.String())
 	if err != nil {
-		return nil, errors.Wrap(err, "fetch page")
+		return nil, fmt.Errorf("fetch page: %w", err)
 	}
-	defer rc.Close()
+	defer resp.Body.Close()
+	p.links = resp.Header.Values("Link")
 
-	doc, err := gq.NewDocumentFromReader(rc)
+	contentType := resp.Header.Get("Content-Type")
+	if strings.HasPrefix(contentType, "image/") {
+		// URL is an image, not a page; return an empty document
+		if p.image, err = p.readImage(u.String(), contentType, resp.Body); err != nil {
+			return nil, err
+		}
+		return gq.NewDocumentFromNode(&html.Node{Type: html.DocumentNode}), nil
+	}
+	if u.Scheme == "file" { // guessed from the file extension
+		contentType = ""
+	}
+	return p.newDocument(p.decodeHTML(resp.Body, contentType))
+}
+
+// return an Icon for image data read from r.
+func (p *parser) readImage(url, contentType string, r io.Reader) (*Icon, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxIconSize))
 	if err != nil {
-		return nil, errors.Wrap(err, "parse HTML")
+		return nil, fmt.Errorf("read image: %w", err)
 	}
-	return p.parse(doc)
+	icon := &Icon{URL: url, MimeType: sniffImageType(data), Kind: KindSite, Source: "direct"}
+	if icon.MimeType == "" {
+		icon.MimeType, _, _ = strings.Cut(contentType, ";")
+	}
+	icon.Width, icon.Height, _ = imageSize(data)
+	p.find.log.Printf("(direct) %s", url)
+	return icon, nil
+}
+
+// number of bytes searched for a <meta> charset declaration.
+const prescanLen = 1024
+
+// return a reader that converts HTML from r to UTF-8. The encoding is
+// determined from a byte-order mark, the Content-Type header or a <meta>
+// charset declaration, in that order.
+func (p *parser) decodeHTML(r io.Reader, contentType string) io.Reader {
+	br := bufio.NewReader(r)
+	b, _ := br.Peek(prescanLen)
+	enc, name, _ := charset.DetermineEncoding(b, contentType)
+	p.find.log.Printf("detected charset %q", name)
+	return transform.NewReader(br, enc.NewDecoder())
 }
 
 // entry point for io.Reader.
-func (p *parser) parseReader(r io.Reader) ([]*Icon, error) {
-	doc, err := gq.NewDocumentFromReader(r)
+func (p *parser) parseReader(r io.Reader) (*Result, error) {
+	doc, err := p.newDocument(r)
 	if err != nil {
-		return nil, errors.Wrap(err, "parse HTML")
+		return nil, err
 	}
 	return p.parse(doc)
 }
 
 // main parser function.
-func (p *parser) parse(doc *gq.Document) ([]*Icon, error) {
+func (p *parser) parse(doc *gq.Document) (*Result, error) {
+	var icons []*Icon
+	for _, fn := range p.sources(doc) {
+		icons = append(icons, fn()...)
+	}
+	return p.finish(icons)
+}
+
+// tidy, filter and sort icons and return them as a Result.
+func (p *parser) finish(icons []*Icon) (*Result, error) {
+	p.result.Icons = p.postProcessIcons(icons)
+	if p.find.failWhenEmpty && len(p.result.Icons) == 0 {
+		return nil, ErrNoIcons
+	}
+	return &p.result, nil
+}
+
+// sources returns a function for each place icons are looked for. The first
+// returns the icons in the HTML document and the page's Link headers; the
+// others request other files.
+func (p *parser) sources(doc *gq.Document) []func() []*Icon {
+	icons, manifestURL := p.parseHTML(doc)
+	icons = append(icons, p.parseLinkHeaders()...)
+	if manifestURL == "" {
+		manifestURL = p.manifestLinkHeader()
+	}
+	sources := []func() []*Icon{
+		func() []*Icon { return icons },
+	}
+
+	// retrieve and parse the AMP or canonical version of the page
+	if p.find.followAMP {
+		if url := p.ampAlternate(doc); url != "" {
+			sources = append(sources, func() []*Icon { return p.parseAlternate(url) })
+		}
+	}
+	// retrieve and parse JSON manifest
+	if !p.find.ignoreManifest {
+		sources = append(sources, func() []*Icon { return p.findManifest(manifestURL) })
+	}
+	// retrieve and parse Windows tile configuration
+	if !p.find.ignoreBrowserConfig {
+		sources = append(sources, p.parseBrowserConfig)
+	}
+	// retrieve and parse oEmbed document
+	if !p.find.ignoreOEmbed {
+		sources = append(sources, p.parseOEmbed)
+	}
+	// look up native app referenced by smart app banner
+	if p.find.appStoreLookup != nil && p.appStoreID != "" {
+		sources = append(sources, p.appSource("app-store", p.find.appStoreLookup, p.appStoreID))
+	}
+	// look up native apps referenced by asset links
+	if p.find.playStoreLookup != nil {
+		sources = append(sources, p.playStoreSource)
+	}
+	// user-supplied discovery strategies
+	page := &Page{URL: p.baseURL, Document: doc}
+	for _, src := range p.find.sources {
+		sources = append(sources, p.customSource(src, page))
+	}
+	// check for existence of URLs like /favicon.ico
+	if !p.find.ignoreWellKnown {
+		sources = append(sources, p.lowValue("well-known", p.findWellKnownIcons))
+	}
+	return sources
+}
+
+// imageSources returns the sources used when the URL is an image instead
+// of an HTML page: the image itself and the well-known locations.
+func (p *parser) imageSources() []func() []*Icon {
+	sources := []func() []*Icon{
+		func() []*Icon { return []*Icon{p.image} },
+	}
+	if !p.find.ignoreWellKnown {
+		sources = append(sources, p.lowValue("well-known", p.findWellKnownIcons))
+	}
+	return sources
+}
+
+// return the URL of the AMP version of the page, or of the canonical
+// version if the page is an AMP page. Returns an empty string if there is
+// no such page, or it is this page.
+func (p *parser) ampAlternate(doc *gq.Document) string {
+	var (
+		root    = doc.Find("html").First()
+		_, amp  = root.Attr("amp")
+		_, bolt = root.Attr("⚡")
+		sel     = "link[rel=amphtml]"
+	)
+	if amp || bolt {
+		sel = "link[rel=canonical]"
+	}
+	if p.baseURL == nil {
+		return ""
+	}
+	href, _ := doc.Find(sel).First().Attr("href")
+	if url := p.absURL(href); url != "" && url != p.baseURL.String() {
+		return url
+	}
+	return ""
+}
+
+// retrieve an alternate version of the page and return the icons declared
+// in its HTML and Link headers.
+func (p *parser) parseAlternate(url string) []*Icon {
+	u, err := urls.Parse(url)
+	if err != nil {
+		p.fail("amp", url, err)
+		return nil
+	}
+	p.find.log.Printf("loading alternate page %q ...", url)
+	pp := p.find.newParser()
+	pp.ctx = p.ctx
+	doc, err := pp.fetchPage(u)
+	if err != nil {
+		p.fail("amp", url, err)
+		return nil
+	}
+	icons, _ := pp.parseHTML(doc)
+	return append(icons, pp.parseLinkHeaders()...)
+}
+
+// extract icons from HTML markup. Also returns the URL of the manifest file,
+// or an empty string if the page doesn't link to one.
+func (p *parser) parseHTML(doc *gq.Document) ([]*Icon, string) {
 	var (
 		icons       []*Icon
-		manifestURL = p.absURL("/manifest.json")
+		manifestURL string
 	)
+	p.configURL = p.absURL("/browserconfig.xml")
+
+	if p.find.noscript {
+		expandNoscript(doc)
+	}
+
+	// other relative URLs are resolved against the document's base URL
+	if href, ok := doc.Find("base[href]").First().Attr("href"); ok {
+		if u, err := urls.Parse(p.absURL(href)); err == nil && u.IsAbs() {
+			p.docBase = u
+		}
+	}
 
 	// icons described in <link../> tags
 	doc.Find("link").Each(func(i int, sel *gq.Selection) {
 		rel, _ := sel.Attr("rel")
 		rel = strings.ToLower(rel)
 		switch rel {
-		// all cases are handled the same way for now
 		case "icon", "alternate icon", "shortcut icon":
-			icons = append(icons, p.parseLink(sel)...)
+			icons = append(icons, p.parseLink(sel, KindSite)...)
 		case "apple-touch-icon", "apple-touch-icon-precomposed":
-			icons = append(icons, p.parseLink(sel)...)
+			icons = append(icons, p.parseLink(sel, KindTouch)...)
 		// site-specific browser apps (https://fluidapp.com/)
 		case "fluid-icon":
-			icons = append(icons, p.parseLink(sel)...)
+			icons = append(icons, p.parseLink(sel, KindApp)...)
+		// Safari pinned tab icons are monochrome SVGs with a separate colour
+		case "mask-icon":
+			icons = append(icons, p.parseLink(sel, KindSite)...)
+		// legacy social image; only used if nothing better is available
+		case "image_src":
+			for _, icon := range p.parseLink(sel, KindImage) {
+				icon.Rank = rankLegacy
+				icons = append(icons, icon)
+			}
+		case "alternate":
+			typ, _ := sel.Attr("type")
+			url, _ := sel.Attr("href")
+			if strings.EqualFold(typ, "application/json+oembed") && p.oembedURL == "" {
+				p.oembedURL = p.absURL(url)
+			}
+		// iOS launch screens
+		case "apple-touch-startup-image":
+			if !p.find.startupImages {
+				return
+			}
+			for _, icon := range p.parseLink(sel, KindStartup) {
+				if icon.Width == 0 {
+					icon.Width, icon.Height, icon.Scale = startupImageSize(icon.Media)
+				}
+				icon.Rank = rankLegacy
+				icons = append(icons, icon)
+			}
 		case "manifest":
 			url, _ := sel.Attr("href")
 			url = p.absURL(url)
 			if url != "" {
 				manifestURL = url
+				p.useCreds = useCredentials(sel.AttrOr("crossorigin", ""))
+			}
+		case "canonical":
+			url, _ := sel.Attr("href")
+			if p.result.CanonicalURL == "" {
+				p.result.CanonicalURL = p.absURL(url)
 			}
 		}
 	})
 
+	p.result.Title = strings.TrimSpace(doc.Find("title").First().Text())
+
 	// OpenGraph (og:) and Twitter <meta../> tags
 	var (
 		// k, v, k, v sequences
 		opengraph []string
 		twitter   []string
+		// Windows Start screen tile
+		tileImage, tileColor string
 	)
 	doc.Find("meta").Each(func(i int, sel *gq.Selection) {
 		if s, ok := sel.Attr("charset"); ok && s != "" {
@@ -100,10 +437,29 @@ func (p *parser) parse(doc *gq.Document) ([]*Icon, error) {
 		}
 
 		prop = strings.ToLower(prop)
+		switch prop {
+		case "apple-itunes-app":
+			p.appStoreID = parseAppleAppID(val)
+		case "theme-color":
+			if p.result.ThemeColor == "" {
+				p.result.ThemeColor = val
+			}
+		case "msapplication-tileimage":
+			tileImage = val
+		case "msapplication-tilecolor":
+			tileColor = val
+		case "msapplication-config":
+			if strings.EqualFold(val, "none") {
+				p.configURL = ""
+			} else if url := p.absURL(val); url != "" {
+				p.configURL = url
+				p.configDeclared = true
+			}
+		}
 		if strings.HasPrefix(prop, "og:image") {
 			opengraph = append(opengraph, prop, val)
 		}
-		if strings.HasPrefix(prop, "twitter:image") {
+		if strings.HasPrefix(prop, "twitter:image") || prop == "twitter:card" {
 			twitter = append(twitter, prop, val)
 		}
 	})
@@ -111,41 +467,55 @@ func (p *parser) parse(doc *gq.Document) ([]*Icon, error) {
 	// find icons in k, v sequences
 	icons = append(icons, p.parseOpenGraph(opengraph)...)
 	icons = append(icons, p.parseTwitter(twitter)...)
+	icons = append(icons, p.parseMicrodata(doc)...)
 
-	// retrieve and parse JSON manifest
-	if !p.find.ignoreManifest {
-		icons = append(icons, p.parseManifest(manifestURL)...)
-	}
-	// check for existence of URLs like /favicon.ico
-	if !p.find.ignoreWellKnown {
-		icons = append(icons, p.findWellKnownIcons()...)
+	if url := p.absURL(tileImage); url != "" {
+		p.find.log.Printf("(msapplication) %s", url)
+		icons = append(icons, &Icon{URL: url, Color: tileColor, Kind: KindTile, Source: "msapplication"})
 	}
 
-	icons = p.postProcessIcons(icons)
+	return icons, manifestURL
+}
 
-	return icons, nil
+// replace <noscript> elements with their contents. HTML parsers that
+// support scripting treat the contents of <noscript> as plain text.
+func expandNoscript(doc *gq.Document) {
+	doc.Find("noscript").Each(func(i int, sel *gq.Selection) {
+		sel.ReplaceWithHtml(sel.Text())
+	})
 }
 
 // extract icons defined in <link../> tags.
-func (p *parser) parseLink(sel *gq.Selection) []*Icon {
+func (p *parser) parseLink(sel *gq.Selection, kind Kind) []*Icon {
+	attrs := map[string]string{}
+	for _, a := range sel.Nodes[0].Attr {
+		attrs[a.Key] = a.Val
+	}
+	if href := p.absURL(attrs["href"]); href != "" {
+		return p.linkIcons(href, attrs, kind, "link")
+	}
+	return nil
+}
+
+// create icons for link to absolute URL href with the given attributes.
+// There is one icon per size in the "sizes" attribute.
+func (p *parser) linkIcons(href string, attrs map[string]string, kind Kind, source string) []*Icon {
 	var (
-		href, _ = sel.Attr("href")
-		typ, _  = sel.Attr("type")
-		size, _ = sel.Attr("sizes")
-		icons   []*Icon
-		icon    = &Icon{}
+		icons []*Icon
+		icon  = &Icon{URL: href, Kind: kind, Source: source}
 	)
-
-	if href = p.absURL(href); href == "" {
-		return nil
+	if p.find.includeAttrs {
+		icon.Attrs = attrs
 	}
-
-	icon.URL = href
 	// icon.FileExt = fileExt(href)
-	if typ != "" {
+	if typ := attrs["type"]; typ != "" {
 		icon.MimeType = typ
 	}
-	if size != "" {
+	icon.Color = attrs["color"]
+	icon.Description = attrs["title"]
+	icon.Media = strings.TrimSpace(attrs["media"])
+	icon.Priority = parsePriority(attrs["fetchpriority"], attrs["importance"])
+	if size := attrs["sizes"]; size != "" {
 		for _, sz := range parseSizes(size) {
 			i := icon.Copy()
 			i.Width, i.Height = sz.w, sz.h
@@ -156,10 +526,67 @@ func (p *parser) parseLink(sel *gq.Selection) []*Icon {
 		icons = append(icons, icon)
 	}
 
-	p.find.log.Printf("(link) %s", icon.URL)
+	p.find.log.Printf("(%s) %s", source, icon.URL)
 	return icons
 }
 
+// return the fetch priority declared by a fetchpriority attribute, or by the
+// obsolete importance attribute if it's not set. Returns "high" or "low", or
+// an empty string for "auto" and invalid values.
+func parsePriority(fetchpriority, importance string) string {
+	s := strings.ToLower(strings.TrimSpace(fetchpriority))
+	if s == "" {
+		s = strings.ToLower(strings.TrimSpace(importance))
+	}
+	if s == "high" || s == "low" {
+		return s
+	}
+	return ""
+}
+
+var (
+	rxDeviceWidth  = regexp.MustCompile(`device-width:\s*(\d+)px`)
+	rxDeviceHeight = regexp.MustCompile(`device-height:\s*(\d+)px`)
+	rxPixelRatio   = regexp.MustCompile(`device-pixel-ratio:\s*(\d+(?:\.\d+)?)`)
+)
+
+// limits of the media queries of startup images; larger values are bogus.
+const (
+	maxDeviceSize = 10000 // CSS pixels
+	maxPixelRatio = 4
+)
+
+// derive the pixel dimensions and scale of a startup image from its media
+// query, e.g. "(device-width: 375px) and (device-height: 812px) and
+// (-webkit-device-pixel-ratio: 3)" is 1125x2436 at 3x.
+func startupImageSize(media string) (int, int, float64) {
+	media = strings.ToLower(media)
+	mw := rxDeviceWidth.FindStringSubmatch(media)
+	mh := rxDeviceHeight.FindStringSubmatch(media)
+	if mw == nil || mh == nil {
+		return 0, 0, 0
+	}
+	w, err := strconv.Atoi(mw[1])
+	if err != nil || w > maxDeviceSize {
+		return 0, 0, 0
+	}
+	h, err := strconv.Atoi(mh[1])
+	if err != nil || h > maxDeviceSize {
+		return 0, 0, 0
+	}
+
+	scale := 1.0
+	if m := rxPixelRatio.FindStringSubmatch(media); m != nil {
+		if n, err1 := strconv.ParseFloat(m[1], 64); err1 == nil && n > 0 {
+			scale = math.Min(n, maxPixelRatio)
+		}
+	}
+	if strings.Contains(media, "orientation: landscape") {
+		w, h = h, w
+	}
+	return int(float64(w) * scale), int(float64(h) * scale), scale
+}
+
 // extract file extension from a URL.
 func fileExt(url string) string {
 	u, err := urls.Parse(url)
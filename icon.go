@@ -7,7 +7,24 @@ package favicon
 import (
 	"crypto/sha256"
 	"fmt"
+	"net"
+	urls "net/url"
 	"sort"
+	"strings"
+)
+
+// Kind classifies Icons by their intended use.
+type Kind string
+
+// Kinds of Icon.
+const (
+	KindSite       Kind = "site"       // favicons, e.g. <link rel="icon"> or /favicon.ico
+	KindTouch      Kind = "touch"      // Apple touch icons
+	KindApp        Kind = "app"        // web app, native app and Fluid icons
+	KindTile       Kind = "tile"       // Windows Start screen tiles
+	KindImage      Kind = "image"      // preview images, e.g. Open Graph or oEmbed
+	KindStartup    Kind = "startup"    // iOS launch screens
+	KindScreenshot Kind = "screenshot" // app screenshots from a manifest
 )
 
 // Icon is a favicon parsed from an HTML file or JSON manifest.
@@ -21,10 +38,61 @@ type Icon struct {
 	// searching for numbers in the URL.
 	Width  int `json:"width"`
 	Height int `json:"height"`
-	// Hash of URL and dimensions to uniquely identify icon.
+	// Pixel density the icon is intended for, e.g. 2 for a "@2x" icon.
+	// Currently only read from manifests; 0 if unknown.
+	Scale float64 `json:"scale,omitempty"`
+	// Colour the icon should be rendered in, e.g. "#5bbad5" for a Safari
+	// pinned tab (mask) icon; empty if not specified.
+	Color string `json:"color,omitempty"`
+	// Media query the icon applies to, e.g. "(prefers-color-scheme: dark)";
+	// empty if it applies to all media. Only read from <link> tags.
+	Media string `json:"media,omitempty"`
+	// Platform the icon is intended for and its accessible name. Only
+	// read from manifests.
+	Platform string `json:"platform,omitempty"`
+	Label    string `json:"label,omitempty"`
+	// Purposes of a manifest icon, e.g. ["maskable", "monochrome"]; empty
+	// if not specified, which means "any".
+	Purpose []string `json:"purpose,omitempty"`
+	// Name of the manifest shortcut the icon belongs to; empty for the
+	// app's own icons. Only set with IncludeShortcuts.
+	Shortcut string `json:"shortcut,omitempty"`
+	// Fetch priority the page gave the icon with a fetchpriority attribute
+	// (or the obsolete importance attribute), "high" or "low"; empty if not
+	// specified or "auto". Only read from <link> tags and Link headers.
+	// Among icons of the same Rank and size, high-priority ones are sorted
+	// first and low-priority ones last.
+	Priority string `json:"priority,omitempty"`
+	// Text describing the image for accessibility, e.g. from og:image:alt,
+	// an <img> element's alt text, or the manifest's label or app name.
+	Description string `json:"description,omitempty"`
+	// Width:height ratio the image is intended to be displayed at, e.g. 2
+	// for a Twitter summary_large_image card; 0 if unknown.
+	AspectRatio float64 `json:"aspect_ratio,omitempty"`
+	// Priority of the icon's source. Icons with a higher Rank are sorted
+	// before those with a lower one, regardless of size. Icons from legacy
+	// sources, e.g. <link rel="image_src">, have a negative Rank.
+	Rank int `json:"rank,omitempty"`
+	// Raw attributes of the <link> element or manifest entry the icon was
+	// read from. Only set if Finder was created with IncludeAttrs.
+	Attrs map[string]string `json:"attrs,omitempty"`
+	// What the icon is for. Empty for Icons from custom Sources that don't
+	// set it.
+	Kind Kind `json:"kind,omitempty"`
+	// Where the icon was found, e.g. "link", "manifest" or "well-known".
+	// Icons from custom Sources have the Source's name.
+	Source string `json:"source,omitempty"`
+	// Hash of URL and dimensions to uniquely identify icon. See CanonicalHash.
 	Hash string `json:"hash"`
+	// Size in bytes and hex-encoded SHA-256 hash of the icon's contents.
+	// Only set by Finder.Inspect.
+	FileSize int    `json:"file_size,omitempty"`
+	Checksum string `json:"checksum,omitempty"`
 }
 
+// Rank of icons from legacy sources.
+const rankLegacy = -1
+
 // String implements Stringer.
 func (i Icon) String() string {
 	return fmt.Sprintf("Icon{\n\tURL: %q,\n\tMimeType: %q,\n\tWidth: %d,\n\tHeight: %d,\n\tHash: %q\n}",
@@ -34,20 +102,159 @@ func (i Icon) String() string {
 // IsSquare returns true if image has equally-long sides.
 func (i Icon) IsSquare() bool { return i.Width == i.Height }
 
+// EffectiveSize returns the icon's dimensions at its intended pixel density,
+// i.e. Width and Height divided by Scale. If Scale is unknown, Width and
+// Height are returned unchanged.
+func (i Icon) EffectiveSize() (int, int) {
+	if i.Scale <= 0 {
+		return i.Width, i.Height
+	}
+	return int(float64(i.Width) / i.Scale), int(float64(i.Height) / i.Scale)
+}
+
 // Copy returns a new Icon with the same values as this one.
 func (i Icon) Copy() *Icon {
 	return &Icon{
-		URL:      i.URL,
-		MimeType: i.MimeType,
-		FileExt:  i.FileExt,
-		Width:    i.Width,
-		Height:   i.Height,
-		Hash:     i.Hash,
+		URL:         i.URL,
+		MimeType:    i.MimeType,
+		FileExt:     i.FileExt,
+		Width:       i.Width,
+		Height:      i.Height,
+		Scale:       i.Scale,
+		Color:       i.Color,
+		Media:       i.Media,
+		Platform:    i.Platform,
+		Label:       i.Label,
+		Purpose:     copyStrings(i.Purpose),
+		Shortcut:    i.Shortcut,
+		Priority:    i.Priority,
+		Description: i.Description,
+		AspectRatio: i.AspectRatio,
+		Rank:        i.Rank,
+		Attrs:       copyAttrs(i.Attrs),
+		Kind:        i.Kind,
+		Source:      i.Source,
+		Hash:        i.Hash,
+		FileSize:    i.FileSize,
+		Checksum:    i.Checksum,
+	}
+}
+
+// return a copy of attribute map m.
+func copyAttrs(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// return a copy of string slice v.
+func copyStrings(v []string) []string {
+	if v == nil {
+		return nil
+	}
+	return append([]string{}, v...)
+}
+
+// Icons is a list of Icons as returned by Finder.
+type Icons []*Icon
+
+// Largest returns the Icon with the most pixels, or nil if v is empty.
+func (v Icons) Largest() *Icon {
+	if len(v) == 0 {
+		return nil
+	}
+	icons := v.sorted()
+	return icons[0]
+}
+
+// Smallest returns the Icon with the fewest pixels, or nil if v is empty.
+// Icons without a known size are only returned if no other Icons have one.
+func (v Icons) Smallest() *Icon {
+	if len(v) == 0 {
+		return nil
+	}
+	icons := v.sorted()
+	for i := len(icons) - 1; i >= 0; i-- {
+		if icons[i].Width > 0 && icons[i].Height > 0 {
+			return icons[i]
+		}
 	}
+	return icons[len(icons)-1]
+}
+
+// Closest returns the Icon whose width is nearest to n. If two Icons are
+// equally near, the larger one is returned. Icons without a known size are
+// only returned if no other Icons have one. Returns nil if v is empty.
+func (v Icons) Closest(n int) *Icon {
+	if len(v) == 0 {
+		return nil
+	}
+	var (
+		best *Icon
+		diff = -1
+	)
+	for _, icon := range v.sorted() {
+		if icon.Width == 0 {
+			continue
+		}
+		d := icon.Width - n
+		if d < 0 {
+			d = -d
+		}
+		if diff == -1 || d < diff {
+			best, diff = icon, d
+		}
+	}
+	if best == nil {
+		return v.Largest()
+	}
+	return best
+}
+
+// SquareOnly returns the square Icons in v. As with the OnlySquare Option,
+// Icons without a known size are also returned.
+func (v Icons) SquareOnly() Icons {
+	return v.FilterBy(func(icon *Icon) *Icon {
+		if !icon.IsSquare() {
+			return nil
+		}
+		return icon
+	})
+}
+
+// FilterBy returns the Icons accepted by Filter.
+func (v Icons) FilterBy(filter Filter) Icons {
+	icons := Icons{}
+	for _, icon := range v {
+		if icon = filter(icon); icon != nil {
+			icons = append(icons, icon)
+		}
+	}
+	return icons
+}
+
+// return a copy of v sorted by pixel count (largest first). Unlike
+// ByPixelCount, Rank is ignored.
+func (v Icons) sorted() Icons {
+	icons := make(Icons, len(v))
+	copy(icons, v)
+	sort.SliceStable(icons, func(i, j int) bool {
+		a, b := icons[i], icons[j]
+		if pa, pb := a.Width*a.Height, b.Width*b.Height; pa != pb {
+			return pa > pb
+		}
+		return lessFormat(a, b)
+	})
+	return icons
 }
 
-// ByWidth sorts icons by width (largest first), and then by image type
-// (PNG > JPEG > SVG > ICO).
+// ByWidth sorts icons by Rank, then by width (largest first), and then by
+// fetch priority, image type (PNG > JPEG > SVG > ICO), URL and source.
 type ByWidth []*Icon
 
 // Implement sort.Interface.
@@ -62,7 +269,7 @@ func formatRank(mimeType string) int {
 		return 10 //nolint:gomnd // .png
 	case "image/jpeg":
 		return 9 //nolint:gomnd // .jpeg
-	case "image/svg":
+	case "image/svg+xml", "image/svg":
 		return 8 //nolint:gomnd // .svg
 	case "image/x-icon":
 		return 7 //nolint:gomnd // .ico
@@ -73,49 +280,121 @@ func formatRank(mimeType string) int {
 	}
 }
 
+// used for sorting icons of the same size
+// higher number = higher priority.
+func priorityRank(priority string) int {
+	switch priority {
+	case "high":
+		return 1
+	case "low":
+		return -1
+	default:
+		return 0
+	}
+}
+
 func (v ByWidth) Less(i, j int) bool {
 	a, b := v[i], v[j]
+	if a.Rank != b.Rank {
+		return a.Rank > b.Rank
+	}
 	if a.Width != b.Width {
 		return a.Width > b.Width
 	}
-	fa, fb := formatRank(a.MimeType), formatRank(a.MimeType)
+	return lessFormat(a, b)
+}
+
+// ByHeight sorts icons by Rank, then by height (largest first), and then by
+// fetch priority, image type (PNG > JPEG > SVG > ICO), URL and source.
+type ByHeight []*Icon
+
+// Implement sort.Interface.
+func (v ByHeight) Len() int      { return len(v) }
+func (v ByHeight) Swap(i, j int) { v[i], v[j] = v[j], v[i] }
+func (v ByHeight) Less(i, j int) bool {
+	a, b := v[i], v[j]
+	if a.Rank != b.Rank {
+		return a.Rank > b.Rank
+	}
+	if a.Height != b.Height {
+		return a.Height > b.Height
+	}
+	return lessFormat(a, b)
+}
+
+// ByPixelCount sorts icons by Rank, then by area (largest first), and then
+// by fetch priority, image type (PNG > JPEG > SVG > ICO), URL and source.
+type ByPixelCount []*Icon
+
+// Implement sort.Interface.
+func (v ByPixelCount) Len() int      { return len(v) }
+func (v ByPixelCount) Swap(i, j int) { v[i], v[j] = v[j], v[i] }
+func (v ByPixelCount) Less(i, j int) bool {
+	a, b := v[i], v[j]
+	if a.Rank != b.Rank {
+		return a.Rank > b.Rank
+	}
+	if pa, pb := a.Width*a.Height, b.Width*b.Height; pa != pb {
+		return pa > pb
+	}
+	return lessFormat(a, b)
+}
+
+// tie-breaker for sorters: order by declared priority, image type, URL,
+// then source, so the order doesn't depend on the order icons were found in.
+func lessFormat(a, b *Icon) bool {
+	if pa, pb := priorityRank(a.Priority), priorityRank(b.Priority); pa != pb {
+		return pa > pb
+	}
+	fa, fb := formatRank(a.MimeType), formatRank(b.MimeType)
 	if fa != fb {
 		return fa > fb
 	}
-	return a.URL < b.URL
+	if a.URL != b.URL {
+		return a.URL < b.URL
+	}
+	return a.Source < b.Source
 }
 
 // Check missing values, remove duplicates, sort.
-func (p *parser) postProcessIcons(icons []*Icon) []*Icon {
+func (p *parser) postProcessIcons(icons []*Icon) Icons {
 	tidied := map[string]*Icon{}
 	for _, icon := range icons {
 		icon.URL = p.absURL(icon.URL)
 
-		if icon.MimeType == "" {
-			icon.MimeType = mimeTypeURL(icon.URL)
+		if isDataURL(icon.URL) {
+			p.inspectDataURL(icon)
+		} else {
+			if icon.MimeType == "" {
+				icon.MimeType = mimeTypeURL(icon.URL)
+			}
+			if icon.FileExt == "" {
+				icon.FileExt = fileExt(icon.URL)
+			}
+			if icon.Width == 0 {
+				if sz := extractSizeFromURL(icon.URL); sz != nil {
+					icon.Width, icon.Height = sz.w, sz.h
+				}
+			}
 		}
 
 		if icon.URL == "" || icon.MimeType == "" {
 			continue
 		}
-
-		if icon.FileExt == "" {
-			icon.FileExt = fileExt(icon.URL)
-		}
-
-		if icon.Width == 0 {
-			if sz := extractSizeFromURL(icon.URL); sz != nil {
-				icon.Width, icon.Height = sz.w, sz.h
-			}
+		icon.Hash = iconHash(icon, p.find.canonicalHash)
+		// keep the first duplicate, unless a later one is better-ranked
+		if other, ok := tidied[icon.Hash]; ok && other.Rank >= icon.Rank {
+			continue
 		}
-		icon.Hash = iconHash(icon)
 		tidied[icon.Hash] = icon
 	}
 
 	icons = []*Icon{}
 	for _, icon := range tidied {
-		for _, fun := range p.find.filters {
-			if icon = fun(icon); icon == nil {
+		url := icon.URL
+		for i, filter := range p.find.filters {
+			if icon = filter.fn(icon); icon == nil {
+				p.find.log.Printf("ignored %s: rejected by filter %s", url, filter.label(i))
 				break
 			}
 		}
@@ -123,13 +402,48 @@ func (p *parser) postProcessIcons(icons []*Icon) []*Icon {
 			icons = append(icons, icon)
 		}
 	}
+	if p.find.strict {
+		icons = p.verifyIcons(icons)
+	}
 
 	sort.Sort(ByWidth(icons))
 	return icons
 }
 
-// returns a hash of icon's URL and size.
-func iconHash(i *Icon) string {
-	s := fmt.Sprintf("%s-%dx%d", i.URL, i.Width, i.Height)
+// returns a hash of icon's URL, size and media query. If canonical is true,
+// the URL is first converted with canonicalURL.
+func iconHash(i *Icon, canonical bool) string {
+	url := i.URL
+	if canonical {
+		url = canonicalURL(url)
+	}
+	s := fmt.Sprintf("%s-%dx%d", url, i.Width, i.Height)
+	if i.Media != "" {
+		s += "-" + i.Media
+	}
 	return fmt.Sprintf("%x", sha256.Sum256([]byte(s)))
 }
+
+// default ports of the schemes canonicalURL removes.
+func defaultPort(scheme string) string {
+	if scheme == "https" {
+		return "443"
+	}
+	return "80"
+}
+
+// return url without http or https scheme, "www." prefix or default port,
+// so the same icon on http://example.com and https://www.example.com has
+// the same canonical URL. Other URLs are returned unchanged.
+func canonicalURL(url string) string {
+	u, err := urls.Parse(url)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return url
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	if port := u.Port(); port != "" && port != defaultPort(u.Scheme) {
+		host = net.JoinHostPort(host, port)
+	}
+	u.Scheme, u.Host = "", host
+	return u.String()
+}
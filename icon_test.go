@@ -7,6 +7,8 @@ package favicon_test
 import (
 	"net/http"
 	"net/http/httptest"
+	"sort"
+	"strings"
 	"testing"
 
 	"github.com/muzhou233/go-favicon"
@@ -15,36 +17,36 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-// TestFormat tests the extraction and parsing of file extensions.
-// NOTE: MIME types aren't tested because Go uses the system MIME
-// database, so results differ between machines.
+// TestFormat tests the extraction and parsing of file extensions and MIME types.
 func TestFormat(t *testing.T) {
 	t.Parallel()
 	tests := []struct {
-		name string
-		path string
-		i    int
-		ext  string
+		name     string
+		path     string
+		i        int
+		ext      string
+		mimeType string
 	}{
 		// read from manifest & markup
-		{"kuli-0", "./testdata/kuli", 0, "png"}, // manifest
-		{"kuli-1", "./testdata/kuli", 1, "png"}, // markup
-		{"kuli-2", "./testdata/kuli", 2, "png"}, // manifest
-		{"kuli-3", "./testdata/kuli", 3, "png"}, // markup
-		{"kuli-6", "./testdata/kuli", 6, "ico"}, // /favicon.ico
+		{"kuli-0", "./testdata/kuli", 0, "png", "image/png"},     // manifest
+		{"kuli-1", "./testdata/kuli", 1, "png", "image/png"},     // markup
+		{"kuli-2", "./testdata/kuli", 2, "png", "image/png"},     // manifest
+		{"kuli-3", "./testdata/kuli", 3, "png", "image/png"},     // markup
+		{"kuli-6", "./testdata/kuli", 6, "svg", "image/svg+xml"}, // mask-icon
+		{"kuli-7", "./testdata/kuli", 7, "ico", "image/x-icon"},  // /favicon.ico
 
 		// read from manifest
-		{"manifest-only-0", "./testdata/manifest-only", 0, "png"},
-		{"manifest-only-1", "./testdata/manifest-only", 1, "png"},
+		{"manifest-only-0", "./testdata/manifest-only", 0, "png", "image/png"},
+		{"manifest-only-1", "./testdata/manifest-only", 1, "png", "image/png"},
 
 		// size parsed from WxH in URL
-		{"mozilla-0", "./testdata/mozilla", 0, "png"},
-		{"mozilla-1", "./testdata/mozilla", 1, "png"},
+		{"mozilla-0", "./testdata/mozilla", 0, "png", "image/png"},
+		{"mozilla-1", "./testdata/mozilla", 1, "png", "image/png"},
 
 		// size parsed from <link>
-		{"multisize-0", "./testdata/multisize", 0, "ico"},
-		{"multisize-1", "./testdata/multisize", 1, "ico"},
-		{"multisize-2", "./testdata/multisize", 2, "ico"},
+		{"multisize-0", "./testdata/multisize", 0, "ico", "image/x-icon"},
+		{"multisize-1", "./testdata/multisize", 1, "ico", "image/x-icon"},
+		{"multisize-2", "./testdata/multisize", 2, "ico", "image/x-icon"},
 	}
 
 	for _, td := range tests {
@@ -60,6 +62,7 @@ func TestFormat(t *testing.T) {
 			require.Greater(t, len(icons), td.i, "too few icons found")
 			icon := icons[td.i]
 			assert.Equal(t, td.ext, icon.FileExt, "unexpected extension")
+			assert.Equal(t, td.mimeType, icon.MimeType, "unexpected MIME type")
 		})
 	}
 }
@@ -94,3 +97,119 @@ func TestIconCopy(t *testing.T) {
 		})
 	}
 }
+
+// TestSort verifies the ordering of the Icon sorters.
+func TestSort(t *testing.T) {
+	t.Parallel()
+	var (
+		ico  = &favicon.Icon{URL: "a.ico", MimeType: "image/x-icon", Width: 32, Height: 32}
+		png  = &favicon.Icon{URL: "b.png", MimeType: "image/png", Width: 32, Height: 32}
+		wide = &favicon.Icon{URL: "c.png", MimeType: "image/png", Width: 64, Height: 16}
+		tall = &favicon.Icon{URL: "d.png", MimeType: "image/png", Width: 16, Height: 48}
+		high = &favicon.Icon{URL: "e.ico", MimeType: "image/x-icon", Width: 32, Height: 32, Priority: "high"}
+		low  = &favicon.Icon{URL: "0.png", MimeType: "image/png", Width: 32, Height: 32, Priority: "low"}
+	)
+
+	tests := []struct {
+		name string
+		sort func([]*favicon.Icon)
+		x    []*favicon.Icon
+	}{
+		{"width", func(v []*favicon.Icon) { sort.Sort(favicon.ByWidth(v)) },
+			[]*favicon.Icon{wide, high, png, ico, low, tall}},
+		{"height", func(v []*favicon.Icon) { sort.Sort(favicon.ByHeight(v)) },
+			[]*favicon.Icon{tall, high, png, ico, low, wide}},
+		{"pixel-count", func(v []*favicon.Icon) { sort.Sort(favicon.ByPixelCount(v)) },
+			[]*favicon.Icon{high, png, wide, ico, low, tall}},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			icons := []*favicon.Icon{ico, tall, wide, png, high, low}
+			td.sort(icons)
+			assert.Equal(t, td.x, icons, "unexpected order")
+		})
+	}
+}
+
+// TestSortTotal verifies icons that differ only by Source always sort the
+// same way, whatever order they were found in.
+func TestSortTotal(t *testing.T) {
+	t.Parallel()
+	var (
+		html     = &favicon.Icon{URL: "a.png", MimeType: "image/png", Width: 32, Height: 32, Source: "html"}
+		manifest = &favicon.Icon{URL: "a.png", MimeType: "image/png", Width: 32, Height: 32, Source: "manifest"}
+		x        = []*favicon.Icon{html, manifest}
+	)
+
+	for _, icons := range [][]*favicon.Icon{{html, manifest}, {manifest, html}} {
+		v := append([]*favicon.Icon{}, icons...)
+		sort.Sort(favicon.ByWidth(v))
+		assert.Equal(t, x, v, "unexpected width order")
+		v = append([]*favicon.Icon{}, icons...)
+		sort.Sort(favicon.ByHeight(v))
+		assert.Equal(t, x, v, "unexpected height order")
+		v = append([]*favicon.Icon{}, icons...)
+		sort.Sort(favicon.ByPixelCount(v))
+		assert.Equal(t, x, v, "unexpected pixel-count order")
+	}
+}
+
+// TestIcons verifies the Icons selection helpers.
+func TestIcons(t *testing.T) {
+	t.Parallel()
+	var (
+		nosize = &favicon.Icon{URL: "a.ico", MimeType: "image/x-icon"}
+		small  = &favicon.Icon{URL: "b.png", MimeType: "image/png", Width: 16, Height: 16}
+		medium = &favicon.Icon{URL: "c.png", MimeType: "image/png", Width: 32, Height: 32}
+		large  = &favicon.Icon{URL: "d.png", MimeType: "image/png", Width: 64, Height: 64}
+		wide   = &favicon.Icon{URL: "e.png", MimeType: "image/png", Width: 48, Height: 24}
+		icons  = favicon.Icons{wide, small, nosize, large, medium}
+		empty  = favicon.Icons{}
+	)
+
+	assert.Equal(t, large, icons.Largest(), "unexpected largest")
+	assert.Equal(t, small, icons.Smallest(), "unexpected smallest")
+	assert.Equal(t, nosize, favicon.Icons{nosize}.Smallest(), "unexpected smallest")
+	assert.Equal(t, medium, icons.Closest(30), "unexpected closest")
+	assert.Equal(t, wide, icons.Closest(40), "unexpected closest")
+	assert.Equal(t, large, icons.Closest(1000), "unexpected closest")
+	assert.Equal(t, favicon.Icons{small, nosize, large, medium}, icons.SquareOnly(), "unexpected square icons")
+	assert.Equal(t, favicon.Icons{wide, small, nosize, large, medium}, icons, "original modified")
+
+	big := icons.FilterBy(func(icon *favicon.Icon) *favicon.Icon {
+		if icon.Width < 32 {
+			return nil
+		}
+		return icon
+	})
+	assert.Equal(t, favicon.Icons{wide, large, medium}, big, "unexpected filtered icons")
+
+	assert.Nil(t, empty.Largest(), "expected nil")
+	assert.Nil(t, empty.Smallest(), "expected nil")
+	assert.Nil(t, empty.Closest(32), "expected nil")
+}
+
+// TestCanonicalHash verifies Hash can ignore URL scheme and "www." prefix.
+func TestCanonicalHash(t *testing.T) {
+	t.Parallel()
+	html := `<link rel="icon" href="https://www.example.com/favicon.ico">
+		<link rel="icon" href="http://example.com/favicon.ico">`
+
+	icons, err := htmlFinder(t).FindReader(strings.NewReader(html))
+	require.Nil(t, err, "unexpected error")
+	require.Equal(t, 2, len(icons), "unexpected favicon count")
+	assert.NotEqual(t, icons[0].Hash, icons[1].Hash, "unexpected hash")
+
+	icons, err = htmlFinder(t, favicon.CanonicalHash).FindReader(strings.NewReader(html))
+	require.Nil(t, err, "unexpected error")
+	require.Equal(t, 1, len(icons), "unexpected favicon count")
+	assert.Equal(t, "https://www.example.com/favicon.ico", icons[0].URL, "unexpected URL")
+
+	other, err := htmlFinder(t, favicon.CanonicalHash).FindReader(strings.NewReader(`<link rel="icon" href="http://example.com/favicon.ico">`))
+	require.Nil(t, err, "unexpected error")
+	require.Equal(t, 1, len(other), "unexpected favicon count")
+	assert.Equal(t, icons[0].Hash, other[0].Hash, "unexpected hash")
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+// Created on 2026-10-16
+
+package favicon
+
+import (
+	"crypto/sha256"
+	"fmt"
+)
+
+// Inspect retrieves the icon at iconURL and returns it with its MIME type,
+// dimensions, FileSize and Checksum read from its contents. Use it for icon
+// URLs from elsewhere, e.g. user input, that weren't found by Finder. data:
+// URLs are also accepted.
+//
+// The MIME type is taken from the file extension if the contents aren't a
+// recognised image format. An error is returned if the icon can't be
+// retrieved or its type determined.
+func (f *Finder) Inspect(iconURL string) (*Icon, error) {
+	u, err := parseURLString(iconURL)
+	if err != nil {
+		return nil, err
+	}
+	p := f.forURL(u).newParser()
+	p.baseURL = u
+
+	icon := &Icon{URL: u.String(), Source: "inspect"}
+	data, err := p.iconData(icon)
+	if err != nil {
+		return nil, err
+	}
+
+	if icon.MimeType = sniffImageType(data); icon.MimeType == "" {
+		if icon.MimeType = mimeTypeURL(icon.URL); icon.MimeType == "" {
+			return nil, fmt.Errorf("unknown image type: %s", iconURL)
+		}
+	}
+	if !isDataURL(icon.URL) {
+		icon.FileExt = fileExt(icon.URL)
+	}
+	icon.Width, icon.Height, _ = imageSize(data)
+	icon.FileSize = len(data)
+	icon.Checksum = fmt.Sprintf("%x", sha256.Sum256(data))
+	icon.Hash = iconHash(icon, f.canonicalHash)
+	return icon, nil
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+// Created on 2026-10-16
+
+package favicon_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/muzhou233/go-favicon"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestInspect verifies metadata is read from the contents of single icons.
+func TestInspect(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.FileServer(http.Dir("./testdata/strict")))
+	t.Cleanup(ts.Close)
+
+	tests := []struct {
+		name, url     string
+		mimeType, ext string
+		width, size   int
+		checksum      string
+		xerr          bool
+	}{
+		{"png", ts.URL + "/good-16x16.png", "image/png", "png", 16, 81,
+			"2169ed7371fc3e2026d26d20f7373c16e2b0d0545a08bf14aaf53292ccd6d842", false},
+		{"svg", ts.URL + "/icon.svg", "image/svg+xml", "svg", 0, 96, "", false},
+		{"extension only", ts.URL + "/not-an-image.png", "image/png", "png", 0, 36, "", false},
+		{"data URL", "data:image/svg+xml,%3Csvg%2F%3E", "image/svg+xml", "", 0, 6,
+			"d4dc56669143034f31aa309635d4113d9ad76a02b1739da22c965ed2049be9e6", false},
+		{"missing", ts.URL + "/missing.png", "", "", 0, 0, "", true},
+		{"unknown type", ts.URL + "/index.html", "", "", 0, 0, "", true},
+	}
+
+	f := favicon.New(favicon.WithClient(ts.Client()), favicon.WithLogger(debugLogger{t}))
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			icon, err := f.Inspect(td.url)
+			if td.xerr {
+				assert.NotNil(t, err, "expected error")
+				return
+			}
+			require.Nil(t, err, "unexpected error")
+			assert.Equal(t, td.url, icon.URL, "unexpected URL")
+			assert.Equal(t, td.mimeType, icon.MimeType, "unexpected MIME type")
+			assert.Equal(t, td.ext, icon.FileExt, "unexpected extension")
+			assert.Equal(t, td.width, icon.Width, "unexpected width")
+			assert.Equal(t, td.width, icon.Height, "unexpected height")
+			assert.Equal(t, td.size, icon.FileSize, "unexpected file size")
+			assert.Len(t, icon.Checksum, 64, "unexpected checksum")
+			if td.checksum != "" {
+				assert.Equal(t, td.checksum, icon.Checksum, "unexpected checksum")
+			}
+			assert.NotEmpty(t, icon.Hash, "no hash")
+		})
+	}
+}
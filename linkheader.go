@@ -0,0 +1,171 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+// Created on 2026-10-16
+
+package favicon
+
+import (
+	urls "net/url"
+	"strings"
+)
+
+// a link from an HTTP Link header (RFC 8288), e.g.
+// Link: </favicon.svg>; rel="icon"; type="image/svg+xml".
+type linkHeader struct {
+	URL    string
+	Params map[string]string // keys are lowercase
+}
+
+// extract icons from the page's Link headers. Relative URLs are resolved
+// against the page's URL, not the document's <base>.
+func (p *parser) parseLinkHeaders() []*Icon {
+	var icons []*Icon
+	for _, link := range parseLinkHeader(p.links) {
+		kind := linkHeaderKind(link.Params["rel"])
+		href := p.linkHeaderURL(link)
+		if kind == "" || href == "" {
+			continue
+		}
+		attrs := map[string]string{"href": link.URL}
+		for k, v := range link.Params {
+			attrs[k] = v
+		}
+		icons = append(icons, p.linkIcons(href, attrs, kind, "link-header")...)
+	}
+	return icons
+}
+
+// return the Kind of icon a Link header's rel parameter declares, or an
+// empty Kind if it isn't an icon. Unlike a <link> tag's rel, the parameter
+// may contain several relation types, e.g. rel="preload icon" (RFC 8288).
+func linkHeaderKind(rel string) Kind {
+	var kind Kind
+	for _, s := range strings.Fields(strings.ToLower(rel)) {
+		switch s {
+		case "apple-touch-icon", "apple-touch-icon-precomposed":
+			return KindTouch
+		case "fluid-icon":
+			return KindApp
+		case "icon", "mask-icon":
+			kind = KindSite
+		}
+	}
+	return kind
+}
+
+// return true if rel contains relation type name.
+func hasRel(rel, name string) bool {
+	for _, s := range strings.Fields(rel) {
+		if strings.EqualFold(s, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// return the URL of the manifest in the page's Link headers, or an empty
+// string if there is none.
+func (p *parser) manifestLinkHeader() string {
+	for _, link := range parseLinkHeader(p.links) {
+		if hasRel(link.Params["rel"], "manifest") {
+			if url := p.linkHeaderURL(link); url != "" {
+				p.useCreds = useCredentials(link.Params["crossorigin"])
+				return url
+			}
+		}
+	}
+	return ""
+}
+
+// resolve link's URL against the page's URL. Returns an empty string if
+// it's invalid.
+func (p *parser) linkHeaderURL(link linkHeader) string {
+	if p.baseURL == nil {
+		return ""
+	}
+	u, err := urls.Parse(link.URL)
+	if err != nil {
+		return ""
+	}
+	return p.baseURL.ResolveReference(u).String()
+}
+
+// parse the values of Link headers. Invalid links are skipped.
+func parseLinkHeader(values []string) []linkHeader {
+	var links []linkHeader
+	for _, s := range values {
+		for {
+			var link linkHeader
+			s = strings.TrimLeft(s, " \t,")
+			if !strings.HasPrefix(s, "<") {
+				break
+			}
+			end := strings.IndexByte(s, '>')
+			if end < 0 {
+				break
+			}
+			link.URL, s = strings.TrimSpace(s[1:end]), s[end+1:]
+			link.Params, s = parseLinkParams(s)
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
+// parse ;-separated key=value parameters up to the comma that ends a link.
+// Returns the parameters and the remainder of s.
+func parseLinkParams(s string) (map[string]string, string) {
+	params := map[string]string{}
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if !strings.HasPrefix(s, ";") {
+			return params, s
+		}
+		s = strings.TrimLeft(s[1:], " \t")
+
+		i := strings.IndexAny(s, "=;,")
+		if i < 0 {
+			i = len(s)
+		}
+		key := strings.ToLower(strings.TrimSpace(s[:i]))
+		s = s[i:]
+
+		var val string
+		if strings.HasPrefix(s, "=") {
+			s = strings.TrimLeft(s[1:], " \t")
+			if strings.HasPrefix(s, `"`) {
+				val, s = parseQuoted(s)
+			} else {
+				j := strings.IndexAny(s, ";,")
+				if j < 0 {
+					j = len(s)
+				}
+				val, s = strings.TrimSpace(s[:j]), s[j:]
+			}
+		}
+		// only the first occurrence of a parameter counts
+		if _, ok := params[key]; !ok && key != "" {
+			params[key] = val
+		}
+	}
+}
+
+// parse a quoted string at the start of s. Returns the unquoted string and
+// the remainder of s.
+func parseQuoted(s string) (string, string) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				b.WriteByte(s[i])
+			}
+		case '"':
+			return b.String(), s[i+1:]
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), ""
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+// Created on 2026-10-16
+
+package favicon_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/muzhou233/go-favicon"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLinkHeader verifies icons are read from the page's Link headers.
+func TestLinkHeader(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Link", `</static/style.css>; rel=preload; as=style, </favicon.svg>; rel="icon"; type="image/svg+xml"`)
+		w.Header().Add("Link", `<https://cdn.example.com/touch.png>; rel=apple-touch-icon; sizes="120x120 180x180"`)
+		w.Header().Add("Link", `</favicon-32.png>; rel="preload icon"; as=image; sizes=32x32`)
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<html><head><base href="https://example.com/">` +
+			`<link rel="icon" href="favicon.png"></head></html>`))
+	}))
+	defer ts.Close()
+
+	f := favicon.New(
+		favicon.WithClient(ts.Client()),
+		favicon.WithLogger(debugLogger{t}),
+		favicon.IgnoreManifest,
+		favicon.IgnoreWellKnown,
+		favicon.IgnoreBrowserConfig,
+	)
+	icons, err := f.Find(ts.URL + "/page/")
+	require.Nil(t, err, "unexpected error")
+	require.Equal(t, 5, len(icons), "unexpected favicon count")
+
+	tests := []struct {
+		url, source string
+		kind        favicon.Kind
+		width       int
+	}{
+		{"https://cdn.example.com/touch.png", "link-header", favicon.KindTouch, 180},
+		{"https://cdn.example.com/touch.png", "link-header", favicon.KindTouch, 120},
+		{ts.URL + "/favicon-32.png", "link-header", favicon.KindSite, 32},
+		{"https://example.com/favicon.png", "link", favicon.KindSite, 0},
+		{ts.URL + "/favicon.svg", "link-header", favicon.KindSite, 0},
+	}
+	for i, td := range tests {
+		assert.Equal(t, td.url, icons[i].URL, "unexpected URL")
+		assert.Equal(t, td.source, icons[i].Source, "unexpected source")
+		assert.Equal(t, td.kind, icons[i].Kind, "unexpected kind")
+		assert.Equal(t, td.width, icons[i].Width, "unexpected width")
+	}
+}
+
+// TestManifestLinkHeader verifies the manifest is found via a Link header if
+// the page doesn't link to it.
+func TestManifestLinkHeader(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name, html string
+		xurl       string
+	}{
+		{"header only", `<html><head></head></html>`, "/app/icon-192.png"},
+		{"HTML first", `<html><head><link rel="manifest" href="/other.json"></head></html>`, "/other-192.png"},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			mux := http.NewServeMux()
+			mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("Link", `</app/app.webmanifest>; rel="manifest"`)
+				w.Header().Set("Content-Type", "text/html")
+				_, _ = w.Write([]byte(td.html))
+			})
+			mux.HandleFunc("/app/app.webmanifest", func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(`{"icons": [{"src": "icon-192.png", "sizes": "192x192"}]}`))
+			})
+			mux.HandleFunc("/other.json", func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(`{"icons": [{"src": "other-192.png", "sizes": "192x192"}]}`))
+			})
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			f := favicon.New(
+				favicon.WithClient(ts.Client()),
+				favicon.WithLogger(debugLogger{t}),
+				favicon.IgnoreWellKnown,
+				favicon.IgnoreBrowserConfig,
+			)
+			icons, err := f.Find(ts.URL + "/page/")
+			require.Nil(t, err, "unexpected error")
+			require.Equal(t, 1, len(icons), "unexpected favicon count")
+			assert.Equal(t, ts.URL+td.xurl, icons[0].URL, "unexpected URL")
+		})
+	}
+}
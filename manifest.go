@@ -5,17 +5,132 @@
 package favicon
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
+	"math"
+	"net/http"
 	urls "net/url"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
+	"strings"
 )
 
 // Manifest is the relevant parts of a manifest.json file.
 type Manifest struct {
-	Icons []ManifestIcon `json:"icons"`
+	Name            string         `json:"name"`
+	ShortName       string         `json:"short_name"`
+	ThemeColor      string         `json:"theme_color"`
+	BackgroundColor string         `json:"background_color"`
+	Icons           []ManifestIcon `json:"icons"`
+	Shortcuts       []Shortcut     `json:"shortcuts"`
+	Screenshots     []ManifestIcon `json:"screenshots"`
+}
+
+// Shortcut is an app shortcut from a manifest.json file, e.g. an entry in
+// the menu shown when the app's icon is right-clicked.
+type Shortcut struct {
+	Name      string         `json:"name"`
+	ShortName string         `json:"short_name"`
+	URL       string         `json:"url"`
+	Icons     []ManifestIcon `json:"icons"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It also accepts icons in the
+// form used by browser extension manifests, a map of sizes to URLs, e.g.
+// {"16": "icon16.png", "128": "icon128.png"}, and a single icon instead of
+// a list. Invalid icons and screenshots are skipped.
+func (m *Manifest) UnmarshalJSON(data []byte) error {
+	type alias Manifest
+	v := struct {
+		*alias
+		Icons       json.RawMessage `json:"icons"`
+		Screenshots json.RawMessage `json:"screenshots"`
+	}{alias: (*alias)(m)}
+
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	icons, err := parseManifestIcons(v.Icons)
+	m.Icons = icons
+	screenshots, err1 := parseManifestIcons(v.Screenshots)
+	m.Screenshots = screenshots
+	if err == nil {
+		err = err1
+	}
+	return err
+}
+
+// parse a manifest's icons: a list of icons, a single icon or a browser
+// extension's map of sizes to URLs. Map entries are sorted by size. Icons
+// that can't be decoded are skipped, and the first error is returned.
+func parseManifestIcons(data json.RawMessage) ([]ManifestIcon, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return nil, nil
+	}
+
+	var list []json.RawMessage
+	if data[0] == '{' {
+		var m map[string]json.RawMessage
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, err
+		}
+		_, src := m["src"]
+		_, url := m["url"]
+		if !src && !url {
+			return parseIconMap(m)
+		}
+		list = []json.RawMessage{data}
+	} else if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+
+	var (
+		icons    []ManifestIcon
+		firstErr error
+	)
+	for _, raw := range list {
+		var mi ManifestIcon
+		if err := json.Unmarshal(raw, &mi); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("manifest icon: %w", err)
+			}
+			continue
+		}
+		icons = append(icons, mi)
+	}
+	return icons, firstErr
+}
+
+// convert a browser extension's map of sizes to URLs to icons. Entries
+// whose URL isn't a string are skipped and reported in the error.
+func parseIconMap(m map[string]json.RawMessage) ([]ManifestIcon, error) {
+	var (
+		icons []ManifestIcon
+		sizes []int
+		byNum = map[int]string{}
+		err   error
+	)
+	for k, v := range m {
+		var url string
+		if json.Unmarshal(v, &url) != nil {
+			err = fmt.Errorf("manifest icon %q: URL is not a string", k)
+			continue
+		}
+		if n, err := strconv.Atoi(k); err == nil && n > 0 && byNum[n] == "" {
+			sizes = append(sizes, n)
+			byNum[n] = url
+		}
+	}
+	sort.Ints(sizes)
+	for _, n := range sizes {
+		icons = append(icons, ManifestIcon{URL: byNum[n], RawSizes: fmt.Sprintf("%dx%d", n, n)})
+	}
+	return icons, err
 }
 
 // ManifestIcon is an icon from a manifest.json file.
@@ -23,25 +138,214 @@ type ManifestIcon struct {
 	URL      string `json:"src"`
 	Type     string `json:"type"`
 	RawSizes string `json:"sizes"`
+	// Platform the icon is intended for, e.g. "windows" or "play".
+	Platform string `json:"platform"`
+	// Purpose is a space-separated list of purposes, e.g. "maskable".
+	Purpose string `json:"purpose"`
+	// Label is an accessible name for the icon.
+	Label string `json:"label"`
+	// FormFactor of a screenshot, "wide" or "narrow"; empty for icons.
+	FormFactor string `json:"form_factor"`
+	// Density is the deprecated pixel density the icon is intended for.
+	// It may be specified as a number or a string; 0 if not set.
+	Density float64 `json:"density"`
+
+	attrs map[string]string // all members of the entry
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It accepts density as either
+// a number or a string, and tolerates common mistakes: the URL in "url"
+// instead of "src", and sizes as a number, e.g. 192 for "192x192", or a
+// list.
+func (mi *ManifestIcon) UnmarshalJSON(data []byte) error {
+	type alias ManifestIcon
+	v := struct {
+		*alias
+		URL     string          `json:"url"`
+		Sizes   json.RawMessage `json:"sizes"`
+		Density json.RawMessage `json:"density"`
+	}{alias: (*alias)(mi)}
+
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if mi.URL == "" {
+		mi.URL = v.URL
+	}
+	mi.RawSizes = parseRawSizes(v.Sizes)
+	mi.Density = parseDensity(v.Density)
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	mi.attrs = make(map[string]string, len(raw))
+	for k, v := range raw {
+		var s string
+		if json.Unmarshal(v, &s) != nil {
+			s = string(v) // not a string; keep JSON text
+		}
+		mi.attrs[k] = s
+	}
+	return nil
+}
+
+// parse the sizes of a manifest icon, normally a string like "16x16 32x32".
+// Numbers, e.g. 192 or "192", are treated as square sizes, and lists of
+// sizes are joined.
+func parseRawSizes(data json.RawMessage) string {
+	var (
+		list []json.RawMessage
+		v    []string
+	)
+	if json.Unmarshal(data, &list) != nil {
+		list = []json.RawMessage{data}
+	}
+	for _, raw := range list {
+		s := strings.TrimSpace(strings.Trim(string(raw), `"`))
+		if n, err := strconv.Atoi(s); err == nil && n > 0 {
+			s = fmt.Sprintf("%dx%d", n, n)
+		} else if json.Unmarshal(raw, &s) != nil {
+			continue
+		}
+		v = append(v, s)
+	}
+	return strings.Join(v, " ")
+}
+
+// highest density accepted in manifests; Android's highest is xxxhdpi (4).
+const maxDensity = 4
+
+// parse a JSON number or string as a density. Returns 0 if invalid. Larger
+// densities than maxDensity are reduced to it.
+func parseDensity(data json.RawMessage) float64 {
+	s := strings.Trim(string(data), `"`)
+	n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || math.IsNaN(n) || math.IsInf(n, 0) || n <= 0 {
+		return 0
+	}
+	return math.Min(n, maxDensity)
+}
+
+// split purpose into its unique, lowercase values.
+func parsePurpose(s string) []string {
+	var (
+		v    []string
+		seen = map[string]bool{}
+	)
+	for _, p := range strings.Fields(strings.ToLower(s)) {
+		if !seen[p] {
+			seen[p] = true
+			v = append(v, p)
+		}
+	}
+	return v
 }
 
+// size of an Android launcher icon at density 1.
+const launcherIconSize = 48
+
 type size struct {
 	w, h int
 }
 
-func (p *parser) parseManifest(url string) []*Icon {
+// manifestNames are common names of manifest files in server roots. They
+// are tried in order if a page doesn't link to its manifest.
+func manifestNames() []string {
+	return []string{
+		"manifest.json",
+		"site.webmanifest",
+		"manifest.webmanifest",
+	}
+}
+
+// retrieve and parse the manifest at url, or if url is empty, the first
+// manifest found at a common location.
+func (p *parser) findManifest(url string) []*Icon {
+	if url != "" {
+		icons, _ := p.parseManifest(url, false)
+		return icons
+	}
+	// like /favicon.ico, common locations are on the page's server, even
+	// if the document's <base> is elsewhere
+	for _, name := range manifestNames() {
+		if icons, ok := p.parseManifest(resolveURL(p.baseURL, "/"+name), true); ok {
+			return icons
+		}
+	}
+	return nil
+}
+
+// return true if a crossorigin attribute asks for credentials to be sent.
+// As in browsers, a missing or invalid value means they aren't.
+func useCredentials(crossorigin string) bool {
+	return strings.EqualFold(strings.TrimSpace(crossorigin), "use-credentials")
+}
+
+// return true if credentials may be sent with the manifest at url, i.e. it
+// has the same origin as the page or is on a host passed to
+// WithManifestCredentials.
+func (p *parser) credentialsAllowed(url string) bool {
+	u, err := urls.Parse(url)
+	if err != nil {
+		return false
+	}
+	if sameOrigin(u, p.baseURL) {
+		return true
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, h := range p.find.manifestHosts {
+		if host == h {
+			return true
+		}
+	}
+	return false
+}
+
+// retrieve and parse manifest. Returns false if it couldn't be retrieved.
+// If probe is true, url is a common location rather than one the page
+// linked to, and it's not an error if there's no manifest there.
+func (p *parser) parseManifest(url string, probe bool) ([]*Icon, bool) {
 	p.find.log.Printf("loading manifest %q ...", url)
-	rc, err := p.find.fetchURL(url)
+	find := p.find
+	if p.useCreds && len(find.manifestHeader) > 0 && !p.credentialsAllowed(url) {
+		find.log.Printf("[WARNING] not sending credentials to %s: not the page's origin", url)
+	} else if p.useCreds && len(find.manifestHeader) > 0 {
+		find.log.Printf("sending credentials with manifest request")
+		find = find.With(func(f *Finder) {
+			if f.header == nil {
+				f.header = http.Header{}
+			}
+			for k, v := range f.manifestHeader {
+				f.header[k] = append(f.header[k], v...)
+			}
+		})
+	}
+	rc, err := find.fetchURL(p.ctx, url)
 	if err != nil {
-		p.find.log.Printf("[ERROR] parse manifest: %v", err)
-		return nil
+		if probe {
+			p.probeFail("manifest", url, err)
+		} else {
+			p.fail("manifest", url, err)
+		}
+		return nil, false
 	}
 	defer rc.Close()
 
-	return p.parseManifestReader(rc)
+	p.mu.Lock()
+	p.result.ManifestURL = url
+	p.mu.Unlock()
+	icons, err := p.parseManifestReader(rc, url)
+	if err != nil {
+		p.fail("manifest", url, err)
+	}
+	return icons, true
 }
 
-func (p *parser) parseManifestReader(r io.Reader) []*Icon {
+// extract icons from manifest. Icons are returned even if there's an error,
+// as the manifest may be partially decoded. Icon URLs are relative to
+// manifestURL, or to the page if it is empty.
+func (p *parser) parseManifestReader(r io.Reader, manifestURL string) ([]*Icon, error) {
 	var (
 		icons []*Icon
 		man   = Manifest{}
@@ -50,22 +354,92 @@ func (p *parser) parseManifestReader(r io.Reader) []*Icon {
 
 	dec := json.NewDecoder(r)
 	if err = dec.Decode(&man); err != nil {
-		p.find.log.Printf("[ERROR] parse manifest: %v", err)
+		err = fmt.Errorf("parse manifest: %w", err)
+	}
+	p.mu.Lock()
+	p.result.Manifest = &man
+	p.result.ManifestName = man.Name
+	p.result.ManifestShortName = man.ShortName
+	p.result.ManifestThemeColor = man.ThemeColor
+	p.result.ManifestBackgroundColor = man.BackgroundColor
+	p.mu.Unlock()
+
+	// describe icons with the app's name if they have no label
+	name := man.Name
+	if name == "" {
+		name = man.ShortName
+	}
+	resolve := p.absURL
+	if base, err1 := urls.Parse(manifestURL); err1 == nil && manifestURL != "" {
+		resolve = func(url string) string { return resolveURL(base, url) }
 	}
 	for _, mi := range man.Icons {
-		// TODO: make URL relative to manifest, not page
-		mi.URL = p.absURL(mi.URL)
-		p.find.log.Printf("(manifest) %s", mi.URL)
-		for _, sz := range parseSizes(mi.RawSizes) {
-			icon := &Icon{
-				URL:    mi.URL,
-				Width:  sz.w,
-				Height: sz.h,
+		icons = append(icons, p.manifestIcons(mi, resolve, name, "")...)
+	}
+	if p.find.shortcuts {
+		for _, sc := range man.Shortcuts {
+			name := sc.Name
+			if name == "" {
+				name = sc.ShortName
+			}
+			for _, mi := range sc.Icons {
+				icons = append(icons, p.manifestIcons(mi, resolve, name, name)...)
+			}
+		}
+	}
+	if p.find.screenshots {
+		for _, mi := range man.Screenshots {
+			for _, icon := range p.manifestIcons(mi, resolve, name, "") {
+				icon.Kind = KindScreenshot
+				icon.Rank = rankLegacy
+				icons = append(icons, icon)
 			}
-			icons = append(icons, icon)
 		}
 	}
 
+	return icons, err
+}
+
+// return an Icon for each size of a manifest icon. description is used if
+// the icon has no label, and shortcut is the name of the shortcut the icon
+// belongs to, if any.
+func (p *parser) manifestIcons(mi ManifestIcon, resolve func(string) string, description, shortcut string) []*Icon {
+	var icons []*Icon
+	if mi.URL = resolve(mi.URL); mi.URL == "" {
+		return nil
+	}
+	p.find.log.Printf("(manifest) %s", mi.URL)
+	// the declared type is authoritative; the URL may have no extension
+	mimeType, _, _ := strings.Cut(strings.ToLower(mi.Type), ";")
+	mimeType = strings.TrimSpace(mimeType)
+	sizes := parseSizes(mi.RawSizes)
+	if len(sizes) == 0 && mi.Density > 0 {
+		// legacy Android manifests may only specify density
+		n := int(launcherIconSize * math.Min(mi.Density, maxDensity))
+		sizes = []size{{w: n, h: n}}
+	}
+	for _, sz := range sizes {
+		icon := &Icon{
+			URL:      mi.URL,
+			MimeType: mimeType,
+			Width:    sz.w,
+			Height:   sz.h,
+			Scale:    mi.Density,
+			Platform: mi.Platform,
+			Label:    mi.Label,
+			Purpose:  parsePurpose(mi.Purpose),
+			Shortcut: shortcut,
+			Kind:     KindApp,
+			Source:   "manifest",
+		}
+		if icon.Description = mi.Label; icon.Description == "" {
+			icon.Description = description
+		}
+		if p.find.includeAttrs {
+			icon.Attrs = copyAttrs(mi.attrs)
+		}
+		icons = append(icons, icon)
+	}
 	return icons
 }
 
@@ -90,20 +464,25 @@ func parseSizes(s string) []size {
 	return sizes
 }
 
-// find dimensions in URL.
+// find dimensions in URL. Its path and query are unescaped first, so that
+// e.g. "icon%2032x32.png" is 32x32, not 2032x32.
 func extractSizeFromURL(url string) *size {
+	u, err := urls.Parse(url)
+	if err != nil {
+		return nil
+	}
+
 	// try to find WxH pattern
-	v := parseSizes(url)
+	s := u.Path
+	if q, err1 := urls.QueryUnescape(u.RawQuery); err1 == nil && q != "" {
+		s += "?" + q
+	}
+	v := parseSizes(s)
 	if len(v) > 0 {
 		return &v[0]
 	}
 
 	// look for -NNN at end of filename
-	u, err := urls.Parse(url)
-	if err != nil {
-		return nil
-	}
-
 	var (
 		name = filepath.Base(u.Path)
 		ext  = filepath.Ext(name)
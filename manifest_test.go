@@ -5,8 +5,13 @@
 package favicon_test
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/muzhou233/go-favicon"
@@ -63,3 +68,534 @@ func TestParseSize(t *testing.T) {
 		})
 	}
 }
+
+// TestManifestDensity verifies the parsing of manifest icon densities.
+func TestManifestDensity(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.FileServer(http.Dir("./testdata/density")))
+	defer ts.Close()
+
+	f := favicon.New(favicon.WithClient(ts.Client()), favicon.WithLogger(debugLogger{t}), favicon.IgnoreWellKnown)
+	icons, err := f.Find(ts.URL + "/index.html")
+	require.Nil(t, err, "unexpected error")
+	require.Equal(t, 5, len(icons), "unexpected favicon count")
+
+	tests := []struct {
+		scale     float64
+		w, ew, eh int
+	}{
+		{0, 512, 512, 512},
+		{4, 192, 48, 48},
+		{2, 96, 48, 48},
+		{1, 48, 48, 48},
+		{0.75, 36, 48, 48}, // size derived from density
+	}
+	for i, td := range tests {
+		icon := icons[i]
+		w, h := icon.EffectiveSize()
+		assert.Equal(t, td.w, icon.Width, "unexpected width")
+		assert.Equal(t, td.scale, icon.Scale, "unexpected scale")
+		assert.Equal(t, td.ew, w, "unexpected effective width")
+		assert.Equal(t, td.eh, h, "unexpected effective height")
+	}
+}
+
+// TestManifestInvalidDensity verifies non-finite densities are ignored and
+// huge ones are limited, so results can still be encoded as JSON.
+func TestManifestInvalidDensity(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/manifest.json" {
+			_, _ = w.Write([]byte(`{"icons": [
+				{"src": "a.png", "sizes": "192x192", "density": "NaN"},
+				{"src": "b.png", "sizes": "192x192", "density": "-Inf"},
+				{"src": "c.png", "sizes": "192x192", "density": 1e300},
+				{"src": "d.png", "density": "1e300"}
+			]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`<link rel="manifest" href="/manifest.json">`))
+	}))
+	defer ts.Close()
+
+	f := favicon.New(favicon.WithClient(ts.Client()), favicon.WithLogger(debugLogger{t}),
+		favicon.IgnoreWellKnown, favicon.IgnoreBrowserConfig)
+	res, err := f.FindDetailed(ts.URL + "/")
+	require.Nil(t, err, "unexpected error")
+	require.Equal(t, 4, len(res.Icons), "unexpected favicon count")
+	for _, icon := range res.Icons {
+		x := 0.0
+		if strings.HasSuffix(icon.URL, "/c.png") || strings.HasSuffix(icon.URL, "/d.png") {
+			x = 4
+		}
+		assert.Equal(t, x, icon.Scale, "unexpected scale for "+icon.URL)
+		// size derived from density is limited too
+		assert.Equal(t, 192, icon.Width, "unexpected width for "+icon.URL)
+	}
+	_, err = json.Marshal(res)
+	assert.Nil(t, err, "unexpected JSON error")
+}
+
+// TestManifestPlatform verifies the parsing and filtering of icon platforms.
+func TestManifestPlatform(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name   string
+		opts   []favicon.Option
+		xicons [][2]string // platform, label
+	}{
+		{"all", nil, [][2]string{{"play", ""}, {"", "Generic icon"}, {"windows", "Windows tile"}}},
+		{"windows", []favicon.Option{favicon.OnlyPlatform("windows")}, [][2]string{{"windows", "Windows tile"}}},
+		{"windows-or-play", []favicon.Option{favicon.OnlyPlatform("windows", "play")},
+			[][2]string{{"play", ""}, {"windows", "Windows tile"}}},
+		{"ios", []favicon.Option{favicon.OnlyPlatform("ios")}, nil},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			ts := httptest.NewServer(http.FileServer(http.Dir("./testdata/platform")))
+			defer ts.Close()
+
+			opts := []favicon.Option{favicon.WithClient(ts.Client()), favicon.WithLogger(debugLogger{t}), favicon.IgnoreWellKnown}
+			icons, err := favicon.New(append(opts, td.opts...)...).Find(ts.URL + "/index.html")
+			require.Nil(t, err, "unexpected error")
+
+			var found [][2]string
+			for _, icon := range icons {
+				found = append(found, [2]string{icon.Platform, icon.Label})
+			}
+			assert.Equal(t, td.xicons, found, "unexpected icons")
+		})
+	}
+}
+
+// TestManifestDescription verifies manifest icons are described by their
+// label or the app's name.
+func TestManifestDescription(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.FileServer(http.Dir("./testdata/platform")))
+	defer ts.Close()
+
+	f := favicon.New(favicon.WithClient(ts.Client()), favicon.WithLogger(debugLogger{t}), favicon.IgnoreWellKnown)
+	icons, err := f.Find(ts.URL + "/index.html")
+	require.Nil(t, err, "unexpected error")
+
+	var found []string
+	for _, icon := range icons {
+		found = append(found, icon.Description)
+	}
+	assert.Equal(t, []string{"Platform", "Generic icon", "Windows tile"}, found, "unexpected descriptions")
+}
+
+// TestManifestFallback verifies common manifest locations are tried if a
+// page doesn't link to its manifest.
+func TestManifestFallback(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name, path string
+		xname      string // manifest name
+		xurl       string // manifest URL
+	}{
+		{"site.webmanifest", "./testdata/webmanifest", "Web Manifest", "/site.webmanifest"},
+		{"manifest.webmanifest", "./testdata/webmanifest/vite", "Vite", "/manifest.webmanifest"},
+		{"manifest.json", "./testdata/no-markup", "No Markup", "/manifest.json"},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			ts := httptest.NewServer(http.FileServer(http.Dir(td.path)))
+			defer ts.Close()
+
+			f := favicon.New(favicon.WithClient(ts.Client()), favicon.WithLogger(debugLogger{t}),
+				favicon.IgnoreWellKnown, favicon.IgnoreBrowserConfig)
+			res, err := f.FindDetailed(ts.URL + "/index.html")
+			require.Nil(t, err, "unexpected error")
+			assert.Equal(t, td.xname, res.ManifestName, "unexpected manifest name")
+			assert.Equal(t, ts.URL+td.xurl, res.ManifestURL, "unexpected manifest URL")
+			require.NotNil(t, res.Manifest, "no manifest")
+			assert.Equal(t, td.xname, res.Manifest.Name, "unexpected manifest name")
+
+			var n int
+			for _, icon := range res.Icons {
+				if icon.Source == "manifest" {
+					n++
+				}
+			}
+			assert.Equal(t, 2, n, "unexpected manifest icon count")
+
+			// locations without a manifest aren't failures
+			assert.Nil(t, res.Err(), "unexpected error")
+		})
+	}
+}
+
+// TestManifestFallbackBase verifies common manifest locations are on the
+// page's server, even if the document's <base> is on another host.
+func TestManifestFallbackBase(t *testing.T) {
+	t.Parallel()
+	var (
+		mu    sync.Mutex
+		paths []string
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/page.html":
+			_, _ = w.Write([]byte(`<html><head><base href="https://cdn.example.com/assets/"></head></html>`))
+		case "/manifest.json":
+			_, _ = w.Write([]byte(`{"name": "App", "icons": [{"src": "icon.png", "sizes": "192x192"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	client := &http.Client{Transport: rewriteTransport{ts, map[string]string{"cdn.example.com": "/cdn"}}}
+	f := favicon.New(favicon.WithClient(client), favicon.WithLogger(debugLogger{t}),
+		favicon.IgnoreWellKnown, favicon.IgnoreBrowserConfig)
+	res, err := f.FindDetailed(ts.URL + "/page.html")
+	require.Nil(t, err, "unexpected error")
+	assert.Equal(t, ts.URL+"/manifest.json", res.ManifestURL, "unexpected manifest URL")
+	// icons in the manifest are still relative to it
+	require.Equal(t, 1, len(res.Icons), "unexpected favicon count")
+	assert.Equal(t, ts.URL+"/icon.png", res.Icons[0].URL, "unexpected URL")
+	assert.Equal(t, []string{"/page.html", "/manifest.json"}, paths, "unexpected requests")
+}
+
+// TestManifestRelative verifies manifest icon URLs are relative to the
+// manifest, not the page.
+func TestManifestRelative(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.FileServer(http.Dir("./testdata/manifest-subdir")))
+	defer ts.Close()
+
+	f := favicon.New(favicon.WithClient(ts.Client()), favicon.WithLogger(debugLogger{t}), favicon.IgnoreWellKnown)
+	icons, err := f.Find(ts.URL + "/index.html")
+	require.Nil(t, err, "unexpected error")
+
+	var found []string
+	for _, icon := range icons {
+		found = append(found, strings.TrimPrefix(icon.URL, ts.URL))
+	}
+	x := []string{"/root-512.png", "https://cdn.example.com/icon-256.png", "/static/icon-192.png"}
+	assert.Equal(t, x, found, "unexpected icon URLs")
+
+	p, err := favicon.NewParser(ts.URL + "/index.html")
+	require.Nil(t, err, "unexpected error")
+	icons, err = p.ParseManifest(strings.NewReader(`{"icons": [{"src": "a.png", "sizes": "16x16"}]}`), "/static/manifest.json")
+	require.Nil(t, err, "unexpected error")
+	require.Equal(t, 1, len(icons), "unexpected icon count")
+	assert.Equal(t, ts.URL+"/static/a.png", icons[0].URL, "unexpected URL")
+}
+
+// TestManifestPurpose verifies the purposes of manifest icons are read.
+func TestManifestPurpose(t *testing.T) {
+	t.Parallel()
+	data := `{"icons": [
+		{"src": "any.png", "sizes": "512x512"},
+		{"src": "maskable.png", "sizes": "256x256", "purpose": "maskable"},
+		{"src": "both.png", "sizes": "128x128", "purpose": "Maskable  monochrome maskable"}
+	]}`
+
+	p, err := favicon.NewParser("https://example.com/")
+	require.Nil(t, err, "unexpected error")
+	icons, err := p.ParseManifest(strings.NewReader(data))
+	require.Nil(t, err, "unexpected error")
+	require.Equal(t, 3, len(icons), "unexpected icon count")
+
+	assert.Nil(t, icons[0].Purpose, "unexpected purpose")
+	assert.Equal(t, []string{"maskable"}, icons[1].Purpose, "unexpected purpose")
+	assert.Equal(t, []string{"maskable", "monochrome"}, icons[2].Purpose, "unexpected purpose")
+	assert.Equal(t, icons[2].Purpose, icons[2].Copy().Purpose, "purpose not copied")
+}
+
+// TestManifestShortcuts verifies the icons of app shortcuts are found.
+func TestManifestShortcuts(t *testing.T) {
+	t.Parallel()
+	data := `{
+		"name": "App",
+		"icons": [{"src": "app.png", "sizes": "512x512"}],
+		"shortcuts": [
+			{"name": "Compose", "url": "/new", "icons": [{"src": "compose.svg", "sizes": "96x96", "purpose": "monochrome"}]},
+			{"short_name": "Inbox", "url": "/inbox", "icons": [{"src": "inbox.png", "sizes": "192x192", "label": "Inbox icon"}]},
+			{"name": "Settings", "url": "/settings"}
+		]
+	}`
+	tests := []struct {
+		name string
+		opts []favicon.Option
+		x    [][3]string // URL, Shortcut, Description
+	}{
+		{"default", nil, [][3]string{{"/app.png", "", "App"}}},
+		{"shortcuts", []favicon.Option{favicon.IncludeShortcuts}, [][3]string{
+			{"/app.png", "", "App"},
+			{"/inbox.png", "Inbox", "Inbox icon"},
+			{"/compose.svg", "Compose", "Compose"},
+		}},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			p, err := favicon.NewParser("https://example.com/", td.opts...)
+			require.Nil(t, err, "unexpected error")
+			icons, err := p.ParseManifest(strings.NewReader(data))
+			require.Nil(t, err, "unexpected error")
+
+			var found [][3]string
+			for _, icon := range icons {
+				found = append(found, [3]string{strings.TrimPrefix(icon.URL, "https://example.com"), icon.Shortcut, icon.Description})
+			}
+			assert.Equal(t, td.x, found, "unexpected icons")
+		})
+	}
+}
+
+// TestManifestScreenshots verifies screenshots are only found with
+// IncludeScreenshots, and are sorted after icons.
+func TestManifestScreenshots(t *testing.T) {
+	t.Parallel()
+	data := `{
+		"name": "App",
+		"icons": [{"src": "app.png", "sizes": "192x192"}],
+		"screenshots": [
+			{"src": "wide.webp", "sizes": "1280x720", "type": "image/webp", "form_factor": "wide", "label": "Home"},
+			{"src": "narrow.png", "sizes": "720x1280", "form_factor": "narrow"},
+			{"src": "unsized.png"}
+		]
+	}`
+	tests := []struct {
+		name string
+		opts []favicon.Option
+		x    [][3]string // URL, Kind, Description
+	}{
+		{"default", nil, [][3]string{{"/app.png", "app", "App"}}},
+		{"screenshots", []favicon.Option{favicon.IncludeScreenshots}, [][3]string{
+			{"/app.png", "app", "App"},
+			{"/wide.webp", "screenshot", "Home"},
+			{"/narrow.png", "screenshot", "App"},
+		}},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			p, err := favicon.NewParser("https://example.com/", td.opts...)
+			require.Nil(t, err, "unexpected error")
+			icons, err := p.ParseManifest(strings.NewReader(data))
+			require.Nil(t, err, "unexpected error")
+
+			var found [][3]string
+			for _, icon := range icons {
+				found = append(found, [3]string{strings.TrimPrefix(icon.URL, "https://example.com"), string(icon.Kind), icon.Description})
+			}
+			assert.Equal(t, td.x, found, "unexpected icons")
+		})
+	}
+
+	var man favicon.Manifest
+	require.Nil(t, json.Unmarshal([]byte(data), &man), "unexpected error")
+	require.Equal(t, 3, len(man.Screenshots), "unexpected screenshot count")
+	assert.Equal(t, "wide", man.Screenshots[0].FormFactor, "unexpected form factor")
+}
+
+// TestManifestMetadata verifies the manifest's names and colours are
+// returned.
+func TestManifestMetadata(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name, path          string
+		xname, xshort       string
+		xtheme, xbackground string
+		xurl                string
+	}{
+		{"kuli", "./testdata/kuli", "Kulturliste Düsseldorf", "KuLi", "#fcc004", "#fcc004", "/manifest.json"},
+		{"webmanifest", "./testdata/webmanifest", "Web Manifest", "Manifest", "#ffffff", "#ffffff", "/site.webmanifest"},
+		{"no manifest", "./testdata/mozilla", "", "", "", "", ""},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			ts := httptest.NewServer(http.FileServer(http.Dir(td.path)))
+			defer ts.Close()
+
+			f := favicon.New(favicon.WithClient(ts.Client()), favicon.WithLogger(debugLogger{t}), favicon.IgnoreWellKnown)
+			res, err := f.FindDetailed(ts.URL + "/index.html")
+			require.Nil(t, err, "unexpected error")
+			assert.Equal(t, td.xname, res.ManifestName, "unexpected name")
+			assert.Equal(t, td.xshort, res.ManifestShortName, "unexpected short name")
+			assert.Equal(t, td.xtheme, res.ManifestThemeColor, "unexpected theme colour")
+			assert.Equal(t, td.xbackground, res.ManifestBackgroundColor, "unexpected background colour")
+			if td.xurl == "" {
+				assert.Equal(t, "", res.ManifestURL, "unexpected manifest URL")
+				assert.Nil(t, res.Manifest, "unexpected manifest")
+			} else {
+				assert.Equal(t, ts.URL+td.xurl, res.ManifestURL, "unexpected manifest URL")
+			}
+		})
+	}
+}
+
+// TestManifestType verifies an icon's declared type takes precedence over
+// its URL.
+func TestManifestType(t *testing.T) {
+	t.Parallel()
+	data := `{"icons": [
+		{"src": "icon.png", "sizes": "512x512", "type": "image/webp"},
+		{"src": "/icons/192", "sizes": "192x192", "type": "Image/PNG; charset=binary"},
+		{"src": "icon-96.png", "sizes": "96x96"},
+		{"src": "/icons/48", "sizes": "48x48"}
+	]}`
+
+	p, err := favicon.NewParser("https://example.com/")
+	require.Nil(t, err, "unexpected error")
+	icons, err := p.ParseManifest(strings.NewReader(data))
+	require.Nil(t, err, "unexpected error")
+
+	var found [][2]string
+	for _, icon := range icons {
+		found = append(found, [2]string{icon.URL, icon.MimeType})
+	}
+	x := [][2]string{
+		{"https://example.com/icon.png", "image/webp"},
+		{"https://example.com/icons/192", "image/png"},
+		{"https://example.com/icon-96.png", "image/png"},
+	}
+	assert.Equal(t, x, found, "unexpected icons")
+}
+
+// TestManifestMultiSize verifies a manifest entry with several sizes yields
+// an Icon for each of them.
+func TestManifestMultiSize(t *testing.T) {
+	t.Parallel()
+	data := `{"icons": [{"src": "icon.png", "sizes": "48x48 96x96  192x192", "type": "image/png"}]}`
+	tests := []struct {
+		name string
+		opts []favicon.Option
+		x    []int // widths
+	}{
+		{"all", nil, []int{192, 96, 48}},
+		{"min", []favicon.Option{favicon.MinWidth(96)}, []int{192, 96}},
+		{"max", []favicon.Option{favicon.MaxWidth(64)}, []int{48}},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			p, err := favicon.NewParser("https://example.com/", td.opts...)
+			require.Nil(t, err, "unexpected error")
+			icons, err := p.ParseManifest(strings.NewReader(data))
+			require.Nil(t, err, "unexpected error")
+
+			var widths []int
+			for _, icon := range icons {
+				assert.Equal(t, "https://example.com/icon.png", icon.URL, "unexpected URL")
+				assert.Equal(t, icon.Width, icon.Height, "unexpected height")
+				widths = append(widths, icon.Width)
+			}
+			assert.Equal(t, td.x, widths, "unexpected widths")
+			assert.Equal(t, td.x[0], icons.Largest().Width, "unexpected largest icon")
+		})
+	}
+}
+
+// TestManifestIconMap verifies icons are read from browser extension
+// manifests, which map sizes to URLs.
+func TestManifestIconMap(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name, in string
+		x        []string // icon URL and width
+		xerr     bool
+	}{
+		{"map", `{"name": "Extension", "icons": {"16": "icon16.png", "128": "/img/icon128.png", "48": "icon48.png"}}`,
+			[]string{"/img/icon128.png 128", "/icon48.png 48", "/icon16.png 16"}, false},
+		{"invalid size", `{"icons": {"large": "large.png", "32": "icon32.png"}}`,
+			[]string{"/icon32.png 32"}, false},
+		{"list", `{"icons": [{"src": "icon.png", "sizes": "64x64"}]}`, []string{"/icon.png 64"}, false},
+		{"no icons", `{"name": "Extension"}`, nil, false},
+		{"invalid", `{"icons": {"16": 16}}`, nil, true},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			p, err := favicon.NewParser("https://example.com/")
+			require.Nil(t, err, "unexpected error")
+			icons, err := p.ParseManifest(strings.NewReader(td.in))
+			assert.Equal(t, td.xerr, err != nil, "unexpected error: %v", err)
+
+			var found []string
+			for _, icon := range icons {
+				found = append(found, strings.TrimPrefix(icon.URL, "https://example.com")+" "+strconv.Itoa(icon.Width))
+			}
+			assert.Equal(t, td.x, found, "unexpected icons")
+		})
+	}
+}
+
+// TestManifestTolerant verifies icons are read from non-standard manifests.
+func TestManifestTolerant(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name, in string
+		x        []string // icon URL and size
+	}{
+		{"single icon", `{"icons": {"src": "icon.png", "sizes": "192x192"}}`, []string{"/icon.png 192x192"}},
+		{"url", `{"icons": [{"url": "icon.png", "sizes": "192x192"}]}`, []string{"/icon.png 192x192"}},
+		{"src and url", `{"icons": [{"src": "src.png", "url": "url.png", "sizes": "48x48"}]}`, []string{"/src.png 48x48"}},
+		{"integer size", `{"icons": [{"src": "icon.png", "sizes": 192}]}`, []string{"/icon.png 192x192"}},
+		{"numeric string", `{"icons": [{"src": "icon.png", "sizes": "96"}]}`, []string{"/icon.png 96x96"}},
+		{"list of sizes", `{"icons": [{"src": "icon.png", "sizes": ["32x32", 16]}]}`,
+			[]string{"/icon.png 32x32", "/icon.png 16x16"}},
+		{"null", `{"icons": null}`, nil},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			p, err := favicon.NewParser("https://example.com/")
+			require.Nil(t, err, "unexpected error")
+			icons, err := p.ParseManifest(strings.NewReader(td.in))
+			require.Nil(t, err, "unexpected error")
+
+			var found []string
+			for _, icon := range icons {
+				found = append(found, fmt.Sprintf("%s %dx%d", strings.TrimPrefix(icon.URL, "https://example.com"),
+					icon.Width, icon.Height))
+			}
+			assert.Equal(t, td.x, found, "unexpected icons")
+		})
+	}
+
+	// invalid entries are skipped
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/manifest.json" {
+			_, _ = w.Write([]byte(`{"icons": [{"src": 16}, {"src": "icon.png", "sizes": "64x64"}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`<html><head><link rel="manifest" href="/manifest.json"></head></html>`))
+	}))
+	defer ts.Close()
+
+	f := favicon.New(favicon.WithClient(ts.Client()), favicon.WithLogger(debugLogger{t}),
+		favicon.IgnoreWellKnown, favicon.IgnoreBrowserConfig)
+	res, err := f.FindDetailed(ts.URL + "/")
+	require.Nil(t, err, "unexpected error")
+	require.Equal(t, 1, len(res.Icons), "unexpected favicon count")
+	assert.Equal(t, ts.URL+"/icon.png", res.Icons[0].URL, "unexpected URL")
+	assert.Equal(t, 1, len(res.Errors), "unexpected error count")
+}
@@ -0,0 +1,49 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+// Created on 2026-10-16
+
+package favicon
+
+import (
+	"strconv"
+
+	gq "github.com/PuerkitoBio/goquery"
+)
+
+// extract schema.org logos and images from HTML microdata, i.e. elements
+// with itemprop="logo" or itemprop="image".
+func (p *parser) parseMicrodata(doc *gq.Document) []*Icon {
+	var icons []*Icon
+	doc.Find(`[itemprop~="logo"], [itemprop~="image"]`).Each(func(i int, sel *gq.Selection) {
+		var url string
+		switch gq.NodeName(sel) {
+		case "img":
+			url, _ = sel.Attr("src")
+		case "link":
+			url, _ = sel.Attr("href")
+		case "meta":
+			url, _ = sel.Attr("content")
+		}
+		if url = p.absURL(url); url == "" {
+			return
+		}
+
+		icon := &Icon{URL: url, Kind: KindImage, Source: "microdata"}
+		if icon.Description = sel.AttrOr("alt", ""); icon.Description == "" {
+			icon.Description = sel.AttrOr("title", "")
+		}
+		if s, ok := sel.Attr("width"); ok {
+			if n, err := strconv.ParseInt(s, 10, 32); err == nil && n > 0 {
+				icon.Width = int(n)
+			}
+		}
+		if s, ok := sel.Attr("height"); ok {
+			if n, err := strconv.ParseInt(s, 10, 32); err == nil && n > 0 {
+				icon.Height = int(n)
+			}
+		}
+		p.find.log.Printf("(microdata) %s", icon.URL)
+		icons = append(icons, icon)
+	})
+	return icons
+}
@@ -0,0 +1,48 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+// Created on 2026-10-16
+
+package favicon_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/muzhou233/go-favicon"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMicrodata verifies schema.org logos and images are found.
+func TestMicrodata(t *testing.T) {
+	t.Parallel()
+	html := `<html><head>
+		<link itemprop="image" href="/image.png">
+	</head><body>
+		<div itemscope itemtype="https://schema.org/Organization">
+			<img itemprop="logo" src="/logo.png" width="250" height="100">
+			<meta itemprop="image" content="https://cdn.example.com/photo.jpg">
+			<span itemprop="image">/not-a-url.png</span>
+			<img itemprop="name" src="/name.png">
+		</div>
+	</body></html>`
+
+	icons, err := htmlFinder(t).FindReader(strings.NewReader(html), favicon.BaseURL("https://example.com/"))
+	require.Nil(t, err, "unexpected error")
+	require.Equal(t, 3, len(icons), "unexpected favicon count")
+
+	tests := []struct {
+		url           string
+		width, height int
+	}{
+		{"https://example.com/logo.png", 250, 100},
+		{"https://example.com/image.png", 0, 0},
+		{"https://cdn.example.com/photo.jpg", 0, 0},
+	}
+	for i, td := range tests {
+		assert.Equal(t, td.url, icons[i].URL, "unexpected URL")
+		assert.Equal(t, td.width, icons[i].Width, "unexpected width")
+		assert.Equal(t, td.height, icons[i].Height, "unexpected height")
+	}
+}
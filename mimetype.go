@@ -0,0 +1,72 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+// Created on 2026-10-16
+
+package favicon
+
+import (
+	"strings"
+	"sync"
+)
+
+// builtinMimeTypes maps file extensions to MIME types. A fixed table is
+// used instead of the operating system's MIME database, so results are the
+// same on every machine.
+func builtinMimeTypes() map[string]string {
+	return map[string]string{
+		".apng": "image/apng",
+		".avif": "image/avif",
+		".bmp":  "image/bmp",
+		".cur":  "image/x-icon",
+		".gif":  "image/gif",
+		".heic": "image/heic",
+		".ico":  "image/x-icon",
+		".jfif": "image/jpeg",
+		".jpeg": "image/jpeg",
+		".jpg":  "image/jpeg",
+		".jxl":  "image/jxl",
+		".png":  "image/png",
+		".svg":  "image/svg+xml",
+		".svgz": "image/svg+xml",
+		".tif":  "image/tiff",
+		".tiff": "image/tiff",
+		".webp": "image/webp",
+	}
+}
+
+var (
+	// extension -> MIME type mappings; built-in types plus those added
+	// with RegisterMimeType.
+	//nolint:gochecknoglobals // registry
+	mimeTypes = builtinMimeTypes()
+	//nolint:gochecknoglobals // registry
+	mimeTypesMu sync.RWMutex
+)
+
+// RegisterMimeType sets the MIME type of Icons whose URL has file extension
+// ext, e.g. RegisterMimeType(".jp2", "image/jp2"). It adds to or overrides
+// the built-in table of image types. The extension is case-insensitive and
+// the leading dot is optional.
+//
+// RegisterMimeType is safe to call concurrently with Finder methods.
+func RegisterMimeType(ext, mimeType string) {
+	mimeTypesMu.Lock()
+	defer mimeTypesMu.Unlock()
+	mimeTypes[normaliseExt(ext)] = mimeType
+}
+
+// return MIME type for extension, or an empty string if it's unknown.
+func mimeTypeExt(ext string) string {
+	mimeTypesMu.RLock()
+	defer mimeTypesMu.RUnlock()
+	return mimeTypes[normaliseExt(ext)]
+}
+
+// lowercase extension and ensure it has a leading dot.
+func normaliseExt(ext string) string {
+	ext = strings.ToLower(ext)
+	if ext != "" && !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+// Created on 2026-10-16
+
+package favicon
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// relevant parts of an oEmbed response. Some providers send sizes as strings.
+type oEmbed struct {
+	ThumbnailURL    string          `json:"thumbnail_url"`
+	ThumbnailWidth  json.RawMessage `json:"thumbnail_width"`
+	ThumbnailHeight json.RawMessage `json:"thumbnail_height"`
+}
+
+func (p *parser) parseOEmbed() []*Icon {
+	if p.oembedURL == "" {
+		return nil
+	}
+
+	url := p.oembedURL
+	p.find.log.Printf("loading oEmbed %q ...", url)
+	rc, err := p.find.fetchURL(p.ctx, url)
+	if err != nil {
+		p.fail("oembed", url, err)
+		return nil
+	}
+	defer rc.Close()
+
+	icons, err := p.parseOEmbedReader(rc)
+	if err != nil {
+		p.fail("oembed", url, err)
+	}
+	return icons
+}
+
+// extract thumbnail from oEmbed JSON.
+func (p *parser) parseOEmbedReader(r io.Reader) ([]*Icon, error) {
+	var oe oEmbed
+	if err := json.NewDecoder(r).Decode(&oe); err != nil {
+		return nil, fmt.Errorf("parse oEmbed: %w", err)
+	}
+	if oe.ThumbnailURL == "" {
+		return nil, nil
+	}
+
+	icon := &Icon{
+		URL:    p.absURL(oe.ThumbnailURL),
+		Width:  parseJSONInt(oe.ThumbnailWidth),
+		Height: parseJSONInt(oe.ThumbnailHeight),
+		Kind:   KindImage,
+		Source: "oembed",
+	}
+	p.find.log.Printf("(oembed) %s", icon.URL)
+	return []*Icon{icon}, nil
+}
+
+// parse a JSON number or string as an integer. Returns 0 if invalid or
+// too large for an int32.
+func parseJSONInt(data json.RawMessage) int {
+	s := strings.TrimSpace(strings.Trim(string(data), `"`))
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || !(n > 0 && n <= math.MaxInt32) {
+		return 0
+	}
+	return int(n)
+}
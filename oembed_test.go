@@ -0,0 +1,79 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+// Created on 2026-10-16
+
+package favicon_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/muzhou233/go-favicon"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestOEmbed verifies thumbnails are read from oEmbed documents.
+func TestOEmbed(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.FileServer(http.Dir("./testdata/oembed")))
+	defer ts.Close()
+
+	f := favicon.New(
+		favicon.WithClient(ts.Client()),
+		favicon.WithLogger(debugLogger{t}),
+		favicon.IgnoreManifest,
+		favicon.IgnoreWellKnown,
+		favicon.IgnoreBrowserConfig,
+	)
+	res, err := f.FindDetailed(ts.URL + "/index.html")
+	require.Nil(t, err, "unexpected error")
+	require.Nil(t, res.Err(), "unexpected source error")
+	require.Equal(t, 1, len(res.Icons), "unexpected favicon count")
+
+	icon := res.Icons[0]
+	assert.Equal(t, ts.URL+"/thumbnails/video.jpg", icon.URL, "unexpected URL")
+	assert.Equal(t, "image/jpeg", icon.MimeType, "unexpected MIME type")
+	assert.Equal(t, 480, icon.Width, "unexpected width")
+	assert.Equal(t, 360, icon.Height, "unexpected height")
+
+	// disabled by Option
+	icons, err := f.With(favicon.IgnoreOEmbed).Find(ts.URL + "/index.html")
+	require.Nil(t, err, "unexpected error")
+	assert.Equal(t, 0, len(icons), "unexpected favicon count")
+}
+
+// TestOEmbedInvalidSize verifies bogus thumbnail sizes are ignored.
+func TestOEmbedInvalidSize(t *testing.T) {
+	t.Parallel()
+	for _, size := range []string{`1e300`, `"NaN"`, `-480`, `"wide"`} {
+		size := size
+		t.Run(size, func(t *testing.T) {
+			t.Parallel()
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/oembed.json" {
+					_, _ = w.Write([]byte(`{"thumbnail_url": "/video.jpg", "thumbnail_width": ` + size +
+						`, "thumbnail_height": 360}`))
+					return
+				}
+				_, _ = w.Write([]byte(`<link rel="alternate" type="application/json+oembed" href="/oembed.json">`))
+			}))
+			defer ts.Close()
+
+			f := favicon.New(
+				favicon.WithClient(ts.Client()),
+				favicon.WithLogger(debugLogger{t}),
+				favicon.IgnoreManifest,
+				favicon.IgnoreWellKnown,
+				favicon.IgnoreBrowserConfig,
+			)
+			icons, err := f.Find(ts.URL + "/")
+			require.Nil(t, err, "unexpected error")
+			require.Equal(t, 1, len(icons), "unexpected favicon count")
+			assert.Equal(t, 0, icons[0].Width, "unexpected width")
+			assert.Equal(t, 360, icons[0].Height, "unexpected height")
+		})
+	}
+}
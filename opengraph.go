@@ -15,25 +15,38 @@ func (p *parser) parseOpenGraph(kv []string) []*Icon {
 	for i := 0; i < len(kv)-1; i += 2 {
 		k, v := kv[i], kv[i+1]
 		switch k {
-		case "og:image":
+		case "og:image", "og:image:url":
+			// og:image:url is an alias, often repeating og:image
+			if icon != nil && k == "og:image:url" && icon.URL == v {
+				continue
+			}
 			if icon != nil {
 				icons = append(icons, icon)
 			}
-			icon = &Icon{URL: v}
+			icon = &Icon{URL: v, Kind: KindImage, Source: "opengraph"}
 			p.find.log.Printf("(opengraph) %s", icon.URL)
+		case "og:image:secure_url":
+			// HTTPS URL of the same image
+			if icon != nil {
+				icon.URL = v
+			}
+		case "og:image:alt":
+			if icon != nil {
+				icon.Description = v
+			}
 		case "og:image:type":
 			if icon != nil {
 				icon.MimeType = v
 			}
 		case "og:image:width":
 			if icon != nil {
-				if n, err := strconv.ParseInt(v, 10, 32); err == nil {
+				if n, err := strconv.ParseInt(v, 10, 32); err == nil && n > 0 {
 					icon.Width = int(n)
 				}
 			}
 		case "og:image:height":
 			if icon != nil {
-				if n, err := strconv.ParseInt(v, 10, 32); err == nil {
+				if n, err := strconv.ParseInt(v, 10, 32); err == nil && n > 0 {
 					icon.Height = int(n)
 				}
 			}
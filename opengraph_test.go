@@ -0,0 +1,42 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+// Created on 2026-10-16
+
+package favicon_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/muzhou233/go-favicon"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestOpenGraph verifies structured og:image properties are read.
+func TestOpenGraph(t *testing.T) {
+	t.Parallel()
+	file, err := os.Open("testdata/opengraph/index.html")
+	require.Nil(t, err, "unexpected error")
+	defer file.Close()
+
+	icons, err := favicon.New(favicon.WithLogger(debugLogger{t})).FindReader(file)
+	require.Nil(t, err, "unexpected error")
+	require.Equal(t, 3, len(icons), "unexpected favicon count")
+
+	tests := []struct {
+		url, mimeType string
+		width, height int
+	}{
+		{"https://example.com/og/first.png", "image/png", 1200, 630},
+		{"https://example.com/og/second.jpg", "image/jpeg", 600, 600},
+		{"https://example.com/og/third.gif", "image/gif", 300, 0},
+	}
+	for i, td := range tests {
+		assert.Equal(t, td.url, icons[i].URL, "unexpected URL")
+		assert.Equal(t, td.mimeType, icons[i].MimeType, "unexpected MIME type")
+		assert.Equal(t, td.width, icons[i].Width, "unexpected width")
+		assert.Equal(t, td.height, icons[i].Height, "unexpected height")
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+// Created on 2026-10-16
+
+package favicon
+
+import (
+	"fmt"
+	"io"
+	urls "net/url"
+)
+
+// Parser extracts icons from individual documents. Use it instead of Finder
+// to build custom pipelines, e.g. to parse HTML from a message queue and
+// probe well-known paths later.
+//
+// Icons returned by Parser are tidied, filtered and sorted the same way as
+// Finder's. A Parser is safe for concurrent use.
+type Parser struct {
+	baseURL *urls.URL
+	find    *Finder
+}
+
+// NewParser creates a Parser that resolves relative URLs against baseURL.
+// Options are the same as for New(); source toggles like IgnoreManifest have
+// no effect, as the caller decides which documents to parse.
+func NewParser(baseURL string, option ...Option) (*Parser, error) {
+	u, err := parseURLString(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	return &Parser{baseURL: u, find: New(option...).forURL(u)}, nil
+}
+
+// BaseURL returns the URL relative URLs are resolved against.
+func (p *Parser) BaseURL() string { return p.baseURL.String() }
+
+// ParseHTML returns the icons declared in HTML (<link> tags, Open Graph and
+// Twitter images). The manifest and other referenced files are not retrieved.
+func (p *Parser) ParseHTML(r io.Reader) (Icons, error) {
+	pp := p.newParser()
+	doc, err := pp.newDocument(r)
+	if err != nil {
+		return nil, err
+	}
+	icons, _ := pp.parseHTML(doc)
+	return pp.postProcessIcons(icons), nil
+}
+
+// ParseManifest returns the icons in a JSON manifest. Relative icon URLs
+// are resolved against manifestURL if it is given, otherwise against the
+// base URL.
+func (p *Parser) ParseManifest(r io.Reader, manifestURL ...string) (Icons, error) {
+	var (
+		pp  = p.newParser()
+		url string
+	)
+	if len(manifestURL) > 0 {
+		url = pp.absURL(manifestURL[0])
+	}
+	icons, err := pp.parseManifestReader(r, url)
+	if err != nil {
+		return nil, err
+	}
+	return pp.postProcessIcons(icons), nil
+}
+
+// WellKnown checks common locations like /favicon.ico on the server at the
+// base URL, which must be absolute.
+func (p *Parser) WellKnown() (Icons, error) {
+	if p.baseURL.Scheme == "" || p.baseURL.Host == "" {
+		return nil, fmt.Errorf("not an absolute URL: %q", p.baseURL)
+	}
+	pp := p.newParser()
+	return pp.postProcessIcons(pp.findWellKnownIcons()), nil
+}
+
+func (p *Parser) newParser() *parser {
+	pp := p.find.newParser()
+	pp.baseURL = p.baseURL
+	return pp
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+// Created on 2026-10-16
+
+package favicon_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/muzhou233/go-favicon"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParser verifies Parser's methods individually.
+func TestParser(t *testing.T) {
+	t.Parallel()
+	p, err := favicon.NewParser("https://github.com/", favicon.WithLogger(debugLogger{t}))
+	require.Nil(t, err, "unexpected error")
+
+	file, err := os.Open("testdata/github/index.html")
+	require.Nil(t, err, "unexpected error")
+	defer file.Close()
+
+	icons, err := p.ParseHTML(file)
+	require.Nil(t, err, "unexpected error")
+	assert.Equal(t, 7, len(icons), "unexpected HTML favicon count")
+	for _, icon := range icons {
+		assert.True(t, strings.HasPrefix(icon.URL, "https://github"), "relative URL: %s", icon.URL)
+	}
+
+	file2, err := os.Open("testdata/github/manifest.json")
+	require.Nil(t, err, "unexpected error")
+	defer file2.Close()
+
+	icons, err = p.ParseManifest(file2)
+	require.Nil(t, err, "unexpected error")
+	assert.Equal(t, 11, len(icons), "unexpected manifest favicon count")
+
+	_, err = p.ParseManifest(strings.NewReader("not JSON"))
+	assert.NotNil(t, err, "expected error")
+}
+
+// TestParserWellKnown verifies Parser probes common locations.
+func TestParserWellKnown(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.FileServer(http.Dir("./testdata/no-markup")))
+	defer ts.Close()
+
+	p, err := favicon.NewParser(ts.URL, favicon.WithClient(ts.Client()), favicon.WithLogger(debugLogger{t}))
+	require.Nil(t, err, "unexpected error")
+	icons, err := p.WellKnown()
+	require.Nil(t, err, "unexpected error")
+	assert.Equal(t, 1, len(icons), "unexpected favicon count")
+
+	p, err = favicon.NewParser("/relative")
+	require.Nil(t, err, "unexpected error")
+	_, err = p.WellKnown()
+	assert.NotNil(t, err, "expected error")
+}
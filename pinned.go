@@ -0,0 +1,36 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+// Created on 2026-10-16
+
+package favicon
+
+// PinIcons configures Finder to return the given icon URLs instead of
+// searching the page. Combine it with ForDomain to maintain manual overrides
+// for sites whose icons can't be found automatically:
+//
+//	f := favicon.New(
+//		favicon.ForDomain("example.com", favicon.PinIcons("https://cdn.example.com/logo-64.png")),
+//	)
+//
+// No page is retrieved, but pinned Icons are otherwise treated like found
+// ones: their type and size are guessed from their URLs, and they are
+// filtered, checked (with Strict) and sorted. Relative URLs are resolved
+// against the URL passed to Find.
+func PinIcons(url ...string) Option {
+	return func(f *Finder) {
+		f.pinned = append(f.pinned, url...)
+	}
+}
+
+// return Icons for pinned URLs.
+func (p *parser) pinnedIcons() []*Icon {
+	var icons []*Icon
+	for _, url := range p.find.pinned {
+		if url = p.absURL(url); url == "" {
+			continue
+		}
+		p.find.log.Printf("(pinned) %s", url)
+		icons = append(icons, &Icon{URL: url, Kind: KindSite, Source: "pinned"})
+	}
+	return icons
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+// Created on 2026-10-16
+
+package favicon_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/muzhou233/go-favicon"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPinIcons verifies pinned icons replace discovery.
+func TestPinIcons(t *testing.T) {
+	t.Parallel()
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		http.NotFound(w, r)
+	}))
+	defer ts.Close()
+
+	f := favicon.New(
+		favicon.WithClient(ts.Client()),
+		favicon.WithLogger(debugLogger{t}),
+		favicon.ForDomain("127.0.0.1", favicon.PinIcons("/img/icon-32x32.png", "https://cdn.example.com/logo.svg")),
+	)
+	icons, err := f.Find(ts.URL + "/index.html")
+	require.Nil(t, err, "unexpected error")
+	require.Equal(t, 2, len(icons), "unexpected favicon count")
+	assert.Equal(t, int32(0), atomic.LoadInt32(&requests), "unexpected requests")
+
+	assert.Equal(t, ts.URL+"/img/icon-32x32.png", icons[0].URL, "unexpected URL")
+	assert.Equal(t, "image/png", icons[0].MimeType, "unexpected MIME type")
+	assert.Equal(t, 32, icons[0].Width, "unexpected width")
+	assert.Equal(t, "pinned", icons[0].Source, "unexpected source")
+	assert.Equal(t, "https://cdn.example.com/logo.svg", icons[1].URL, "unexpected URL")
+
+	// filters still apply
+	icons, err = f.With(favicon.OnlyMimeType("image/svg+xml")).Find(ts.URL + "/")
+	require.Nil(t, err, "unexpected error")
+	require.Equal(t, 1, len(icons), "unexpected favicon count")
+	assert.Equal(t, "https://cdn.example.com/logo.svg", icons[0].URL, "unexpected URL")
+
+	// streamed too
+	s, err := f.FindStream(context.Background(), ts.URL+"/")
+	require.Nil(t, err, "unexpected error")
+	var n int
+	for range s.C {
+		n++
+	}
+	assert.Equal(t, 2, n, "unexpected favicon count")
+	assert.Equal(t, int32(0), atomic.LoadInt32(&requests), "unexpected requests")
+}
@@ -0,0 +1,43 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+// Created on 2026-10-16
+
+package favicon
+
+// ReaderOption configures a single call to FindReader.
+type ReaderOption func(*readerConfig)
+
+// settings for one call to FindReader.
+type readerConfig struct {
+	baseURL string
+	charset string
+	options []Option
+}
+
+// BaseURL sets the URL of the page being read, which relative URLs are
+// resolved against (unless the page has a <base> element). The URL's
+// fragment is ignored.
+//
+// If the base URL is not set or is relative, Icon URLs may be relative,
+// and files referenced by the page, such as the manifest, cannot be
+// retrieved. Well-known locations like /favicon.ico are only checked if
+// the base URL is absolute.
+func BaseURL(url string) ReaderOption {
+	return func(c *readerConfig) { c.baseURL = url }
+}
+
+// Charset sets the character encoding of the HTML, e.g. "windows-1252" or
+// "shift_jis". Any name in the WHATWG Encoding Standard is accepted. By
+// default, HTML is assumed to be UTF-8; use FindBytes to detect the
+// encoding instead.
+func Charset(name string) ReaderOption {
+	return func(c *readerConfig) { c.charset = name }
+}
+
+// WithOptions applies Finder options to this call only, e.g. to skip the
+// manifest of one page:
+//
+//	icons, err := f.FindReader(r, favicon.BaseURL(url), favicon.WithOptions(favicon.IgnoreManifest))
+func WithOptions(option ...Option) ReaderOption {
+	return func(c *readerConfig) { c.options = append(c.options, option...) }
+}
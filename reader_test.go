@@ -0,0 +1,88 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+// Created on 2026-10-16
+
+package favicon_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/muzhou233/go-favicon"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestReaderOptions verifies the per-call options of FindReader.
+func TestReaderOptions(t *testing.T) {
+	t.Parallel()
+	html := `<html><head>
+		<link rel="icon" type="image/png" href="icon.png">
+		<noscript><link rel="icon" type="image/x-icon" href="/favicon.ico"></noscript>
+	</head></html>`
+	tests := []struct {
+		name string
+		opts []favicon.ReaderOption
+		x    []string
+	}{
+		{"no base", nil, []string{"icon.png"}},
+		{"absolute", []favicon.ReaderOption{favicon.BaseURL("https://example.com/dir/page.html")},
+			[]string{"https://example.com/dir/icon.png"}},
+		{"fragment", []favicon.ReaderOption{favicon.BaseURL("https://example.com/dir/#section")},
+			[]string{"https://example.com/dir/icon.png"}},
+		{"relative", []favicon.ReaderOption{favicon.BaseURL("/dir/page.html")}, []string{"/dir/icon.png"}},
+		{"options", []favicon.ReaderOption{
+			favicon.BaseURL("https://example.com/"),
+			favicon.WithOptions(favicon.IncludeNoscript),
+		}, []string{"https://example.com/icon.png", "https://example.com/favicon.ico"}},
+	}
+
+	f := htmlFinder(t)
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			icons, err := f.FindReader(strings.NewReader(html), td.opts...)
+			require.Nil(t, err, "unexpected error")
+			var found []string
+			for _, icon := range icons {
+				found = append(found, icon.URL)
+			}
+			assert.Equal(t, td.x, found, "unexpected favicon URLs")
+		})
+	}
+
+	// options only apply to one call
+	icons, err := f.FindReader(strings.NewReader(html))
+	require.Nil(t, err, "unexpected error")
+	assert.Equal(t, 1, len(icons), "unexpected favicon count")
+
+	_, err = f.FindReader(strings.NewReader(html), favicon.BaseURL("http://[::1"))
+	assert.NotNil(t, err, "expected error for invalid base URL")
+}
+
+// TestReaderCharset verifies HTML is decoded from the specified charset.
+func TestReaderCharset(t *testing.T) {
+	t.Parallel()
+	const x = "https://example.com/%E3%82%A2%E3%82%A4%E3%82%B3%E3%83%B3-32x32.png"
+	data, err := os.ReadFile("testdata/charset/euc-kr.html")
+	require.Nil(t, err, "unexpected error")
+
+	f := htmlFinder(t)
+	icons, err := f.FindReader(strings.NewReader(string(data)),
+		favicon.BaseURL("https://example.com/"), favicon.Charset("EUC-KR"))
+	require.Nil(t, err, "unexpected error")
+	require.Equal(t, 1, len(icons), "unexpected favicon count")
+	assert.Equal(t, x, icons[0].URL, "unexpected favicon URL")
+
+	// Charset overrides detection
+	icons, err = f.FindBytes(data, favicon.BaseURL("https://example.com/"), favicon.Charset("windows-1252"))
+	require.Nil(t, err, "unexpected error")
+	require.Equal(t, 1, len(icons), "unexpected favicon count")
+	assert.NotEqual(t, x, icons[0].URL, "charset not overridden")
+
+	_, err = f.FindReader(strings.NewReader(string(data)), favicon.Charset("klingon"))
+	assert.NotNil(t, err, "expected error for unknown charset")
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+// Created on 2026-10-16
+
+package favicon
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Rule adds locations to check for icons on the sites under a domain, e.g.
+// where a hosting platform puts them.
+type Rule struct {
+	// Domain the rule applies to, including its subdomains, e.g. "github.io".
+	Domain string `json:"domain"`
+	// Paths relative to the server root, e.g. "assets/favicon.png". They are
+	// checked like /favicon.ico, so they are skipped with IgnoreWellKnown.
+	Paths []string `json:"paths"`
+}
+
+// WithRules applies rules to the Finder. Each Rule is the same as:
+//
+//	ForDomain(rule.Domain, WithWellKnownPath(rule.Paths...))
+func WithRules(rule ...Rule) Option {
+	return func(f *Finder) {
+		for _, r := range rule {
+			ForDomain(r.Domain, WithWellKnownPath(r.Paths...))(f)
+		}
+	}
+}
+
+// ReadRules reads a ruleset from JSON, a list of Rules:
+//
+//	[
+//		{"domain": "github.io", "paths": ["favicon.png"]},
+//		{"domain": "myshopify.com", "paths": ["cdn/shop/files/favicon.png"]}
+//	]
+//
+// An error is returned if a Rule has no domain or paths, or has a
+// misspelt field.
+func ReadRules(r io.Reader) ([]Rule, error) {
+	var rules []Rule
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&rules); err != nil {
+		return nil, fmt.Errorf("parse rules: %w", err)
+	}
+	for i, rule := range rules {
+		if rule.Domain == "" || len(rule.Paths) == 0 {
+			return nil, fmt.Errorf("rule #%d: domain and paths are required", i+1)
+		}
+	}
+	return rules, nil
+}
+
+// LoadRules reads a ruleset from a JSON file. See ReadRules.
+func LoadRules(path string) ([]Rule, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadRules(file)
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+// Created on 2026-10-16
+
+package favicon_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/muzhou233/go-favicon"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRules verifies the paths in rules are checked on matching domains.
+func TestRules(t *testing.T) {
+	t.Parallel()
+	rules, err := favicon.LoadRules("./testdata/rules/rules.json")
+	require.Nil(t, err, "unexpected error")
+	require.Equal(t, 2, len(rules), "unexpected rule count")
+
+	var (
+		mu    sync.Mutex
+		paths []string
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		if r.URL.Path == "/static/logo.svg" {
+			_, _ = w.Write([]byte("<svg/>"))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer ts.Close()
+
+	f := favicon.New(favicon.WithClient(ts.Client()), favicon.WithLogger(debugLogger{t}), favicon.WithRules(rules...))
+	icons, err := f.FindWellKnown(ts.URL)
+	require.Nil(t, err, "unexpected error")
+	require.Equal(t, 1, len(icons), "unexpected favicon count")
+	assert.Equal(t, ts.URL+"/static/logo.svg", icons[0].URL, "unexpected URL")
+	assert.Equal(t, []string{"/favicon.ico", "/apple-touch-icon.png", "/assets/img/favicon.png", "/static/logo.svg"},
+		paths, "unexpected requests")
+}
+
+// TestReadRules verifies invalid rulesets are rejected.
+func TestReadRules(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name, in string
+		xerr     bool
+	}{
+		{"valid", `[{"domain": "github.io", "paths": ["favicon.png"]}]`, false},
+		{"empty", `[]`, false},
+		{"not a list", `{"domain": "github.io"}`, true},
+		{"no domain", `[{"paths": ["favicon.png"]}]`, true},
+		{"no paths", `[{"domain": "github.io", "paths": []}]`, true},
+		{"misspelt", `[{"domain": "github.io", "path": ["favicon.png"]}]`, true},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := favicon.ReadRules(strings.NewReader(td.in))
+			assert.Equal(t, td.xerr, err != nil, "unexpected error: %v", err)
+		})
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+// Created on 2026-10-16
+
+package favicon
+
+import (
+	"context"
+	urls "net/url"
+
+	gq "github.com/PuerkitoBio/goquery"
+)
+
+// Page is the HTML page a Finder is searching for icons.
+type Page struct {
+	// URL of the page. May be nil if the page was read from an io.Reader
+	// without a base URL.
+	URL *urls.URL
+	// Document is the parsed HTML.
+	Document *gq.Document
+}
+
+// Source is a custom discovery strategy. Sources are called after the
+// built-in HTML, manifest and well-known searches, and their Icons are
+// filtered, de-duplicated and sorted along with the built-in results.
+// Relative Icon URLs are resolved against the page URL.
+//
+// Add Sources to a Finder by passing WithSource(...) to New().
+type Source interface {
+	// Name identifies the Source in log messages.
+	Name() string
+	// Icons returns the Icons found by the Source. If an error is returned,
+	// it is logged and any Icons are still used.
+	Icons(ctx context.Context, page *Page) ([]*Icon, error)
+}
+
+// WithSource adds custom Sources to the ones searched by Finder.
+func WithSource(source ...Source) Option {
+	return func(f *Finder) {
+		f.sources = append(f.sources, source...)
+	}
+}
+
+// return a source function that calls a custom Source.
+func (p *parser) customSource(src Source, page *Page) func() []*Icon {
+	return func() []*Icon {
+		icons, err := src.Icons(p.ctx, page)
+		if err != nil {
+			p.fail(src.Name(), "", err)
+		}
+		for _, icon := range icons {
+			if icon.Source == "" {
+				icon.Source = src.Name()
+			}
+			p.find.log.Printf("(%s) %s", src.Name(), icon.URL)
+		}
+		return icons
+	}
+}
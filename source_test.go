@@ -0,0 +1,80 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+// Created on 2026-10-16
+
+package favicon_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/muzhou233/go-favicon"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// returns a fixed set of icons.
+type staticSource struct {
+	urls []string
+	err  error
+}
+
+func (s staticSource) Name() string { return "static" }
+
+func (s staticSource) Icons(_ context.Context, page *favicon.Page) ([]*favicon.Icon, error) {
+	if page.Document == nil {
+		return nil, errors.New("no document")
+	}
+	var icons []*favicon.Icon
+	for _, u := range s.urls {
+		icons = append(icons, &favicon.Icon{URL: u})
+	}
+	return icons, s.err
+}
+
+// TestSource verifies custom Sources are searched.
+func TestSource(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name   string
+		src    staticSource
+		xcount int
+	}{
+		{"none", staticSource{}, 6},
+		{"one", staticSource{urls: []string{"/static/custom-64x64.png"}}, 7},
+		{"duplicate", staticSource{urls: []string{"/static/custom-64x64.png", "/static/custom-64x64.png"}}, 7},
+		{"error", staticSource{urls: []string{"/static/custom-64x64.png"}, err: errors.New("oops")}, 7},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			ts := httptest.NewServer(http.FileServer(http.Dir("./testdata/kuli")))
+			defer ts.Close()
+
+			f := favicon.New(
+				favicon.WithClient(ts.Client()),
+				favicon.WithLogger(debugLogger{t}),
+				favicon.WithSource(td.src),
+				favicon.IgnoreManifest,
+				favicon.IgnoreWellKnown,
+			)
+			icons, err := f.Find(ts.URL + "/index.html")
+			require.Nil(t, err, "unexpected error")
+			assert.Equal(t, td.xcount, len(icons), "unexpected favicon count")
+
+			for _, icon := range icons {
+				if icon.URL == ts.URL+"/static/custom-64x64.png" {
+					assert.Equal(t, 64, icon.Width, "unexpected width")
+					return
+				}
+			}
+			assert.Empty(t, td.src.urls, "custom icon not found")
+		})
+	}
+}
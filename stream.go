@@ -0,0 +1,96 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+// Created on 2026-10-16
+
+package favicon
+
+import "context"
+
+// Stream delivers the icons found by FindStream. Receive icons from C until
+// it is closed, then call Err.
+type Stream struct {
+	C   <-chan *Icon
+	err error // set before C is closed
+}
+
+// Err returns the error that ended the stream, or nil if all sources
+// completed: ErrNoIcons if Finder has FailWhenEmpty and no icons were found,
+// or the context's error if it was cancelled. Only call Err after C is
+// closed.
+func (s *Stream) Err() error { return s.err }
+
+// FindStream finds favicons for URL and delivers them on the returned Stream
+// as each source (HTML, manifest, well-known paths) completes, rather than
+// waiting for all of them. The HTML page is retrieved before FindStream
+// returns, so an error is returned if it cannot be fetched or parsed.
+//
+// Icons are sorted within each source, but not across sources. The Stream's
+// channel is closed when all sources are done or ctx is cancelled.
+func (f *Finder) FindStream(ctx context.Context, url string) (*Stream, error) {
+	u, err := parseURLString(url)
+	if err != nil {
+		return nil, err
+	}
+	p := f.forURL(u).newParser()
+	p.ctx = ctx
+
+	ch := make(chan *Icon)
+	s := &Stream{C: ch}
+	if len(p.find.pinned) > 0 {
+		p.baseURL = u
+		go p.stream(s, ch, []func() []*Icon{p.pinnedIcons})
+		return s, nil
+	}
+
+	doc, err := p.fetchDocument(u)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.image != nil {
+		go p.stream(s, ch, p.imageSources())
+		return s, nil
+	}
+	go p.stream(s, ch, p.sources(doc))
+	return s, nil
+}
+
+// run sources concurrently and send their icons to ch, skipping duplicates.
+// The error that ends the stream, if any, is set on s before ch is closed.
+func (p *parser) stream(s *Stream, ch chan<- *Icon, sources []func() []*Icon) {
+	defer close(ch)
+
+	results := make(chan []*Icon, len(sources))
+	for _, fn := range sources {
+		fn := fn
+		go func() { results <- fn() }()
+	}
+
+	seen := map[string]bool{}
+	for range sources {
+		var icons []*Icon
+		select {
+		case icons = <-results:
+		case <-p.ctx.Done():
+			s.err = p.ctx.Err()
+			return
+		}
+
+		for _, icon := range p.postProcessIcons(icons) {
+			if seen[icon.Hash] {
+				continue
+			}
+			seen[icon.Hash] = true
+
+			select {
+			case ch <- icon:
+			case <-p.ctx.Done():
+				s.err = p.ctx.Err()
+				return
+			}
+		}
+	}
+	if p.find.failWhenEmpty && len(seen) == 0 {
+		s.err = ErrNoIcons
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+// Created on 2026-10-16
+
+package favicon
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const (
+	maxIconSize = 10 << 20 // maximum number of bytes of an icon read
+	sniffLen    = 1024     // number of bytes searched for an <svg> tag
+)
+
+// return the Icons in v that pass checkIcon. Each URL is retrieved once,
+// even if several Icons (e.g. the sizes of an ICO file) share it, and URLs
+// are retrieved concurrently.
+func (p *parser) verifyIcons(v Icons) Icons {
+	var (
+		byURL = map[string][]int{} // URL -> indices of Icons in v
+		ok    = make([]bool, len(v))
+		wg    sync.WaitGroup
+	)
+	for i, icon := range v {
+		byURL[icon.URL] = append(byURL[icon.URL], i)
+	}
+	for _, indices := range byURL {
+		indices := indices
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data, fetchErr := p.iconData(v[indices[0]])
+			for _, i := range indices {
+				err := fetchErr
+				if err == nil {
+					err = checkIcon(v[i], data)
+				}
+				if err != nil {
+					p.find.log.Printf("[WARNING] ignored %s (%dx%d): %v", v[i].URL, v[i].Width, v[i].Height, err)
+					continue
+				}
+				ok[i] = true
+			}
+		}()
+	}
+	wg.Wait()
+
+	icons := Icons{}
+	for i, icon := range v {
+		if ok[i] {
+			icons = append(icons, icon)
+		}
+	}
+	return icons
+}
+
+// check that icon's contents match its MIME type and size. An ICO file
+// matches if any of the images it contains has the icon's size.
+func checkIcon(icon *Icon, data []byte) error {
+	if mimeType := sniffImageType(data); !sameImageType(mimeType, icon.MimeType) {
+		return fmt.Errorf("MIME type is %q, not %q", mimeType, icon.MimeType)
+	}
+	sizes := imageSizes(data)
+	if len(sizes) == 0 || icon.Width == 0 {
+		return nil
+	}
+	found := make([]string, len(sizes))
+	for i, sz := range sizes {
+		if sz.w == icon.Width && sz.h == icon.Height {
+			return nil
+		}
+		found[i] = fmt.Sprintf("%dx%d", sz.w, sz.h)
+	}
+	return fmt.Errorf("size is %s, not %dx%d", strings.Join(found, ", "), icon.Width, icon.Height)
+}
+
+// return the contents of icon, fetching it if necessary.
+func (p *parser) iconData(icon *Icon) ([]byte, error) {
+	if isDataURL(icon.URL) {
+		_, data, err := parseDataURL(icon.URL)
+		return data, err
+	}
+
+	rc, err := p.find.fetchURL(p.ctx, icon.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, maxIconSize))
+	if err != nil {
+		return nil, fmt.Errorf("read icon: %w", err)
+	}
+	return data, nil
+}
+
+// return MIME type of image data, or an empty string if it's not an image.
+func sniffImageType(data []byte) string {
+	if s := http.DetectContentType(data); strings.HasPrefix(s, "image/") {
+		return s
+	}
+	// DetectContentType doesn't recognise SVG
+	head := data
+	if len(head) > sniffLen {
+		head = head[:sniffLen]
+	}
+	if bytes.Contains(head, []byte("<svg")) {
+		return "image/svg+xml"
+	}
+	return ""
+}
+
+// returns true if MIME types a and b are the same type of image.
+func sameImageType(a, b string) bool {
+	normalise := func(s string) string {
+		switch s {
+		case "image/vnd.microsoft.icon":
+			return "image/x-icon"
+		case "image/svg":
+			return "image/svg+xml"
+		case "image/jpg":
+			return "image/jpeg"
+		}
+		return s
+	}
+	return a != "" && normalise(a) == normalise(b)
+}
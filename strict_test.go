@@ -0,0 +1,94 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+// Created on 2026-10-16
+
+package favicon_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/muzhou233/go-favicon"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStrict verifies inconsistent icons are dropped in Strict mode.
+func TestStrict(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.FileServer(http.Dir("./testdata/strict")))
+	t.Cleanup(ts.Close)
+
+	f := favicon.New(
+		favicon.WithClient(ts.Client()),
+		favicon.WithLogger(debugLogger{t}),
+		favicon.IgnoreManifest,
+		favicon.IgnoreWellKnown,
+		favicon.IgnoreBrowserConfig,
+	)
+	tests := []struct {
+		name string
+		f    *favicon.Finder
+		x    []string
+	}{
+		{"lax", f, []string{"/good-16x16.png", "/icon.svg", "/missing.png", "/not-an-image.png", "/wrong-size.png"}},
+		{"strict", f.With(favicon.Strict), []string{"/good-16x16.png", "/icon.svg"}},
+		{"strict-then-lax", f.With(favicon.Strict, favicon.Lax),
+			[]string{"/good-16x16.png", "/icon.svg", "/missing.png", "/not-an-image.png", "/wrong-size.png"}},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			icons, err := td.f.Find(ts.URL + "/index.html")
+			require.Nil(t, err, "unexpected error")
+
+			var found []string
+			for _, icon := range icons {
+				found = append(found, icon.URL[len(ts.URL):])
+			}
+			assert.ElementsMatch(t, td.x, found, "unexpected icons")
+		})
+	}
+}
+
+// TestStrictMultiSize verifies each size of an ICO file is checked against
+// its directory, and the file is only retrieved once.
+func TestStrictMultiSize(t *testing.T) {
+	t.Parallel()
+	var (
+		mu       sync.Mutex
+		requests = map[string]int{}
+		fs       = http.FileServer(http.Dir("./testdata/multisize"))
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+		fs.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	f := favicon.New(
+		favicon.WithClient(ts.Client()),
+		favicon.WithLogger(debugLogger{t}),
+		favicon.IgnoreManifest,
+		favicon.IgnoreWellKnown,
+		favicon.IgnoreBrowserConfig,
+		favicon.Strict,
+	)
+	icons, err := f.Find(ts.URL + "/")
+	require.Nil(t, err, "unexpected error")
+
+	var widths []int
+	for _, icon := range icons {
+		widths = append(widths, icon.Width)
+	}
+	// the file contains 16x16, 32x32 and 48x48 images, not 24x24
+	assert.Equal(t, []int{48, 16}, widths, "unexpected icon sizes")
+	assert.Equal(t, 1, requests["/favicon.ico"], "unexpected request count")
+}
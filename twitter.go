@@ -6,29 +6,48 @@ package favicon
 
 import "strconv"
 
+// aspect ratios of Twitter card images.
+func cardAspectRatio(card string) float64 {
+	switch card {
+	case "summary":
+		return 1
+	case "summary_large_image":
+		return 2 //nolint:gomnd // 2:1
+	default:
+		return 0
+	}
+}
+
 func (p *parser) parseTwitter(kv []string) []*Icon {
 	var (
 		icons []*Icon
 		icon  *Icon
+		ratio float64
 	)
 	for i := 0; i < len(kv)-1; i += 2 {
 		k, v := kv[i], kv[i+1]
 		switch k {
+		case "twitter:card":
+			ratio = cardAspectRatio(v)
 		case "twitter:image:src", "twitter:image":
 			if icon != nil {
 				icons = append(icons, icon)
 			}
-			icon = &Icon{URL: v}
+			icon = &Icon{URL: v, Kind: KindImage, Source: "twitter"}
 			p.find.log.Printf("(twitter) %s", icon.URL)
+		case "twitter:image:alt":
+			if icon != nil {
+				icon.Description = v
+			}
 		case "twitter:image:width":
 			if icon != nil {
-				if n, err := strconv.ParseInt(v, 10, 32); err == nil {
+				if n, err := strconv.ParseInt(v, 10, 32); err == nil && n > 0 {
 					icon.Width = int(n)
 				}
 			}
 		case "twitter:image:height":
 			if icon != nil {
-				if n, err := strconv.ParseInt(v, 10, 32); err == nil {
+				if n, err := strconv.ParseInt(v, 10, 32); err == nil && n > 0 {
 					icon.Height = int(n)
 				}
 			}
@@ -37,5 +56,8 @@ func (p *parser) parseTwitter(kv []string) []*Icon {
 	if icon != nil {
 		icons = append(icons, icon)
 	}
+	for _, icon := range icons {
+		icon.AspectRatio = ratio
+	}
 	return icons
 }
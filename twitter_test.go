@@ -0,0 +1,45 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+// Created on 2026-10-16
+
+package favicon_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/muzhou233/go-favicon"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTwitterCard verifies card images are annotated with their aspect ratio.
+func TestTwitterCard(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name, html string
+		xratio     float64
+	}{
+		{"large", `<meta name="twitter:card" content="summary_large_image">
+			<meta name="twitter:image" content="https://example.com/card.png">`, 2},
+		{"summary", `<meta name="twitter:image:src" content="https://example.com/card.png">
+			<meta name="twitter:card" content="summary">`, 1},
+		{"no-card", `<meta name="twitter:image" content="https://example.com/card.png">`, 0},
+		{"unknown-card", `<meta name="twitter:card" content="player">
+			<meta name="twitter:image" content="https://example.com/card.png">`, 0},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			html := "<html><head>" + td.html + "</head></html>"
+			icons, err := favicon.New(favicon.WithLogger(debugLogger{t})).FindReader(strings.NewReader(html))
+			require.Nil(t, err, "unexpected error")
+			require.Equal(t, 1, len(icons), "unexpected favicon count")
+			assert.Equal(t, "https://example.com/card.png", icons[0].URL, "unexpected URL")
+			assert.Equal(t, td.xratio, icons[0].AspectRatio, "unexpected aspect ratio")
+		})
+	}
+}
@@ -4,6 +4,12 @@
 
 package favicon
 
+import (
+	"errors"
+	"path"
+	"strings"
+)
+
 // iconNames are common names of icon files hosted in server roots.
 func iconNames() []string {
 	return []string{
@@ -12,25 +18,51 @@ func iconNames() []string {
 	}
 }
 
+// wellKnownDirNames are icon locations in the /.well-known/ directory
+// (RFC 8615) checked with ProbeWellKnownDir.
+func wellKnownDirNames() []string {
+	return []string{
+		".well-known/favicon.ico",
+	}
+}
+
+// WithWellKnownPath adds locations to the common ones like /favicon.ico
+// that are checked for icons. Paths are relative to the server root.
+func WithWellKnownPath(name ...string) Option {
+	return func(f *Finder) {
+		for _, s := range name {
+			if s = strings.TrimLeft(s, "/"); s != "" {
+				f.wellKnownPaths = append(f.wellKnownPaths, s)
+			}
+		}
+	}
+}
+
 func (p *parser) findWellKnownIcons() []*Icon {
 	if p.baseURL == nil {
 		return nil
 	}
 
-	var (
-		icons []*Icon
-		root  = p.baseURL.Scheme + "://" + p.baseURL.Host + "/"
-	)
-	for _, name := range iconNames() {
-		u := root + name
-		r, err := p.find.fetchURL(u)
+	var icons []*Icon
+	for _, name := range append(iconNames(), p.find.wellKnownPaths...) {
+		u := resolveURL(p.baseURL, "/"+name)
+		if u == "" {
+			p.fail("well-known", name, errors.New("invalid path"))
+			continue
+		}
+		r, err := p.find.fetchURL(p.ctx, u)
 		if err != nil {
+			p.probeFail("well-known", u, err)
 			continue
 		}
 		r.Close()
 
+		kind := KindSite
+		if strings.HasPrefix(path.Base(name), "apple-touch-icon") {
+			kind = KindTouch
+		}
 		p.find.log.Printf("(well-known) %s", u)
-		icons = append(icons, &Icon{URL: u})
+		icons = append(icons, &Icon{URL: u, Kind: kind, Source: "well-known"})
 	}
 
 	return icons